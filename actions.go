@@ -4,15 +4,180 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/skybon/multilogger"
+	"github.com/skybon/semaphore"
 )
 
 type serverActions struct {
-	password string
-	core     *Core
-	logs     *multilogger.LogCollection
+	password  string
+	core      *Core
+	logs      *multilogger.LogCollection
+	snapshots *snapshotWriter
+
+	catalogDir     string
+	catalogReload  semaphore.Semaphore
+	catalogWatcher *catalogWatcher
+}
+
+func (s *serverActions) logSnapshotSave(err error) {
+	if err != nil {
+		s.logs.Add(PrettyLogMessage(500, fmt.Sprintf("Snapshot save failed: %s", err.Error()), multilogger.MSG_MAJOR))
+	} else {
+		s.logs.Add(PrettyLogMessage(200, "Snapshot saved.", multilogger.MSG_MINOR))
+	}
+}
+
+func (s *serverActions) logGameTableSave(err error) {
+	s.logs.Add(PrettyLogMessage(500, fmt.Sprintf("Game table save failed: %s", err.Error()), multilogger.MSG_MAJOR))
+}
+
+func (s *serverActions) notifyBuddyEvents(events []BuddyEvent) {
+	for _, event := range events {
+		var text string
+		switch event.Type {
+		case BuddyAppeared:
+			text = fmt.Sprintf("Buddy %s appeared on %s (%s).", event.Buddy.ID, event.Presence.ServerName, event.Presence.Host)
+		case BuddyLeft:
+			text = fmt.Sprintf("Buddy %s left %s (%s).", event.Buddy.ID, event.Previous.ServerName, event.Previous.Host)
+		case BuddySwitched:
+			text = fmt.Sprintf("Buddy %s switched to %s (%s).", event.Buddy.ID, event.Presence.ServerName, event.Presence.Host)
+		}
+		s.logs.Add(PrettyLogMessage(200, text, multilogger.MSG_MAJOR))
+	}
+
+	postWebhooks(s.core.Buddies.Webhooks(), map[string]interface{}{"buddy_events": events}, s.logWebhookError)
+}
+
+func (s *serverActions) notifyAlertEvents(events []AlertEvent) {
+	for _, event := range events {
+		s.logs.Add(PrettyLogMessage(200, fmt.Sprintf("Alert %s fired on %s (%s): %d/%d players on %s.", event.Rule.ID, event.Server.Name, event.Server.Host, event.Server.NumPlayers, event.Server.MaxPlayers, event.Server.Map), multilogger.MSG_MAJOR))
+		postWebhooks(event.Rule.Webhooks, map[string]interface{}{"alert_event": event}, s.logWebhookError)
+	}
+}
+
+func (s *serverActions) logWebhookError(url string, err error) {
+	s.logs.Add(PrettyLogMessage(500, fmt.Sprintf("Webhook %s failed: %s", url, err.Error()), multilogger.MSG_MAJOR))
+}
+
+// enableState loads persisted user state from the directory and keeps saving it there.
+func (s *serverActions) enableState(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	globals, err := LoadGlobalSettings(statePath(dir, "global_settings.json"))
+	if err != nil {
+		return err
+	}
+	s.core.Globals = globals
+
+	buddies, err := LoadBuddyList(statePath(dir, "buddies.json"))
+	if err != nil {
+		return err
+	}
+	buddies.OnEvents = s.notifyBuddyEvents
+	s.core.Buddies = buddies
+
+	alerts, err := LoadAlertCollection(statePath(dir, "alerts.json"))
+	if err != nil {
+		return err
+	}
+	alerts.OnEvents = s.notifyAlertEvents
+	s.core.Alerts = alerts
+
+	blocks, err := LoadBlockList(statePath(dir, "blocklist.json"))
+	if err != nil {
+		return err
+	}
+	s.core.Blocks = blocks
+
+	trash, err := LoadTrash(statePath(dir, "trash.json"))
+	if err != nil {
+		return err
+	}
+	s.core.Trash = trash
+
+	return nil
+}
+
+// enableSnapshots restores the game table from the snapshot file and keeps saving it every interval and on cleanup.
+func (s *serverActions) enableSnapshots(path string, interval time.Duration) {
+	restored, err := LoadSnapshot(s.core.GameTable, path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		s.logs.Add(PrettyLogMessage(500, fmt.Sprintf("Snapshot restore failed: %s", err.Error()), multilogger.MSG_MAJOR))
+	default:
+		s.core.DetectNewInstallations(restored, s.core.storedSettingKeys(restored))
+		for _, id := range restored {
+			s.core.ReindexPlayers(id)
+		}
+		s.logs.Add(PrettyLogMessage(200, fmt.Sprintf("Restored %d games from snapshot.", len(restored)), multilogger.MSG_MAJOR))
+	}
+
+	s.snapshots = startSnapshotWriter(s.core.GameTable, path, interval, s.logSnapshotSave)
+}
+
+// enableCatalogReload makes reloadCatalog read the catalog from the directory, and reloads it whenever its files change if interval is positive.
+func (s *serverActions) enableCatalogReload(dir string, interval time.Duration) {
+	s.catalogDir = dir
+	if interval > 0 {
+		s.catalogWatcher = startCatalogWatcher(dir, interval, s.reloadCatalog)
+	}
+}
+
+// reloadCatalog loads the catalog files again and logs what changed. The current catalog is kept if the files are broken.
+func (s *serverActions) reloadCatalog() {
+	s.catalogReload.Exec(func() {
+		catalog, err := LoadCatalog(s.catalogDir)
+		if err != nil {
+			s.logs.Add(PrettyLogMessage(500, fmt.Sprintf("Catalog reload failed: %s", err.Error()), multilogger.MSG_MAJOR))
+			return
+		}
+
+		changes := s.core.ReloadCatalog(catalog)
+		for _, change := range changes {
+			s.logs.Add(PrettyLogMessage(200, describeCatalogChange(change), multilogger.MSG_MAJOR))
+		}
+		s.logs.Add(PrettyLogMessage(200, fmt.Sprintf("Catalog reloaded: %d games changed.", len(changes)), multilogger.MSG_MAJOR))
+	})
+}
+
+func describeFieldChanges(prefix string, changes map[string]FieldChange) []string {
+	keys := make([]string, 0, len(changes))
+	for k := range changes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	output := make([]string, 0, len(keys))
+	for _, k := range keys {
+		output = append(output, fmt.Sprintf("%s%s %v -> %v", prefix, k, changes[k].Old, changes[k].New))
+	}
+
+	return output
+}
+
+func describeCatalogChange(change CatalogChange) string {
+	if change.Action != CatalogGameChanged {
+		return fmt.Sprintf("Catalog game %s %s.", change.GameID, change.Action)
+	}
+
+	diff := append(describeFieldChanges("", change.Info), describeFieldChanges("default ", change.Defaults)...)
+	text := fmt.Sprintf("Catalog game %s changed: %s.", change.GameID, strings.Join(diff, ", "))
+	if len(change.Updated) > 0 {
+		text += fmt.Sprintf(" Updated in game table: %s.", strings.Join(change.Updated, ", "))
+	}
+	if change.Error != "" {
+		text += fmt.Sprintf(" Game table update failed: %s.", change.Error)
+	}
+
+	return text
 }
 
 func (s *serverActions) renderLogResponse(status int, message string, content interface{}, severity multilogger.LogMessageType, w http.ResponseWriter) {
@@ -71,40 +236,67 @@ func (s *serverActions) checkRequestPassword(w http.ResponseWriter, r *http.Requ
 	s.checkPassword(w, r, inputData.Password, cb)
 }
 
-func (s *serverActions) mergeGameEntry(entry gameEntryPost) error {
+// checkGameEntry applies the post to the game's current info, or to an empty one for a new game, and validates it together with the settings. Problems are reported per field.
+func (s *serverActions) checkGameEntry(entry gameEntryPost) (GameInfo, error) {
 	id := *entry.ID
-	if !s.core.GameTable.CheckGameEntry(id) {
-		return errUnknownGameID
-	}
+	errs := FieldErrors{}
 
 	info, _ := s.core.GameTable.GameInfo(id)
+	if entry.Tags != nil {
+		tags, err := NormalizeTags(*entry.Tags)
+		if err != nil {
+			errs["tags"] = err.Error()
+		}
+		info.Tags = tags
+	}
 	if entry.Name != nil {
 		info.Name = *entry.Name
 	}
+	if entry.Proxy != nil {
+		info.Proxy = ProxyID(*entry.Proxy)
+	}
+	if entry.Adapter != nil {
+		info.Adapter = AdapterID(*entry.Adapter)
+	}
+	if entry.SteamAppID != nil {
+		info.SteamAppID = *entry.SteamAppID
+	}
 
-	proxyP := entry.Proxy
-	if proxyP != nil {
-		proxyID := GetProxyID(*proxyP)
+	if err := s.core.CheckGameInfo(info); err != nil {
+		for k, v := range err.(FieldErrors) {
+			errs[k] = v
+		}
+	}
+	if _, err := s.core.CheckSettings(id, entry.Settings); err != nil {
+		settingErrs, ok := err.(SettingErrors)
+		if !ok {
+			return info, err
+		}
+		for k, v := range settingErrs {
+			errs["settings."+k] = v
+		}
+	}
 
-		info.Proxy = proxyID
+	if len(errs) > 0 {
+		return info, errs
 	}
 
-	adapterP := entry.Adapter
-	if adapterP != nil {
-		adapterID := GetAdapterID(*adapterP)
+	return info, nil
+}
 
-		info.Adapter = adapterID
+func (s *serverActions) mergeGameEntry(entry gameEntryPost) error {
+	id := *entry.ID
+	if !s.core.GameTable.CheckGameEntry(id) {
+		return errUnknownGameID
 	}
-	s.core.GameTable.SetGameInfo(id, info)
 
-	if entry.Settings != nil {
-		s.core.GameTable.ClearSettings(id)
-		for k, v := range entry.Settings {
-			s.core.GameTable.SetSetting(id, k, v)
-		}
+	info, err := s.checkGameEntry(entry)
+	if err != nil {
+		return err
 	}
+	s.core.GameTable.SetGameInfo(id, info)
 
-	return nil
+	return s.core.MergeSettings(id, entry.Settings)
 }
 
 func (s *serverActions) upsertGameEntryBase(w http.ResponseWriter, r *http.Request, create bool) {
@@ -119,6 +311,8 @@ func (s *serverActions) upsertGameEntryBase(w http.ResponseWriter, r *http.Reque
 	}
 
 	var errorMap = map[string]error{}
+	var created []GameID
+	var keep = map[GameID]map[string]bool{}
 
 	for _, entry := range gameData {
 		func(entry gameEntryPost) {
@@ -133,7 +327,10 @@ func (s *serverActions) upsertGameEntryBase(w http.ResponseWriter, r *http.Reque
 			entryID = *entryIDP
 
 			if create {
-				err = s.core.GameTable.CreateGameEntry(entryID)
+				_, err = s.checkGameEntry(entry)
+				if err == nil {
+					err = s.core.GameTable.CreateGameEntry(entryID)
+				}
 			} else {
 				exists := s.core.GameTable.CheckGameEntry(entryID)
 				if !exists {
@@ -144,10 +341,26 @@ func (s *serverActions) upsertGameEntryBase(w http.ResponseWriter, r *http.Reque
 			if err == nil {
 				err = s.mergeGameEntry(entry)
 			}
+
+			if err == nil && create {
+				created = append(created, entryID)
+				keep[entryID] = map[string]bool{}
+				for k := range entry.Settings {
+					keep[entryID][k] = true
+				}
+			}
 			errorMap[entryID.String()] = err
 		}(entry)
 	}
 
+	if len(created) > 0 {
+		for k, v := range s.core.DetectNewInstallations(created, keep) {
+			if v != nil {
+				errorMap[k.String()] = v
+			}
+		}
+	}
+
 	s.renderBatchParseResult(w, errorMap, multilogger.MSG_MAJOR)
 }
 
@@ -170,7 +383,7 @@ func (s *serverActions) deleteGameEntry(w http.ResponseWriter, r *http.Request)
 	} else {
 		outMap := make(map[string]string)
 		for _, id := range ids {
-			err := s.core.GameTable.RemoveGameEntry(GameID(id))
+			_, err := s.core.TrashGame(GameID(id), inputData.User, r.RemoteAddr)
 			if err == nil {
 				outMap[id] = "OK"
 			} else {
@@ -182,6 +395,72 @@ func (s *serverActions) deleteGameEntry(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (s *serverActions) cloneGameEntry(w http.ResponseWriter, r *http.Request) {
+	var inputData gameClonePost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if err := s.core.CloneGame(inputData.Source, inputData.Target, inputData.Servers, inputData.History); err != nil {
+		s.renderLogError(w, err)
+		return
+	}
+
+	s.renderLogResponse(200, fmt.Sprintf("Game %s cloned to %s.", inputData.Source, inputData.Target), map[string]interface{}{"id": inputData.Target}, multilogger.MSG_MAJOR, w)
+}
+
+func (s *serverActions) renameGameEntry(w http.ResponseWriter, r *http.Request) {
+	var inputData gameClonePost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if err := s.core.RenameGame(inputData.Source, inputData.Target); err != nil {
+		s.renderLogError(w, err)
+		return
+	}
+
+	s.renderLogResponse(200, fmt.Sprintf("Game %s renamed to %s.", inputData.Source, inputData.Target), map[string]interface{}{"id": inputData.Target}, multilogger.MSG_MAJOR, w)
+}
+
+func (s *serverActions) renderRegistries(w http.ResponseWriter, r *http.Request) {
+	renderResponse(200, "OK.", s.core.Registries(), w)
+}
+
+func (s *serverActions) readTrash(w http.ResponseWriter, r *http.Request) {
+	renderResponse(200, "OK.", map[string]interface{}{"entries": s.core.Trash.All(), "retention": s.core.Trash.Retention.String()}, w)
+}
+
+func (s *serverActions) restoreTrash(w http.ResponseWriter, r *http.Request) {
+	var inputData trashEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if len(inputData.IDs) == 0 {
+		s.renderError(w, errNoTrashEntriesSpecified)
+		return
+	}
+
+	errorMap := map[string]error{}
+	for _, id := range inputData.IDs {
+		_, errorMap[id] = s.core.RestoreGame(id)
+	}
+
+	s.renderBatchParseResult(w, errorMap, multilogger.MSG_MAJOR)
+}
+
+func (s *serverActions) purgeTrash(w http.ResponseWriter, r *http.Request) {
+	var inputData trashEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if len(inputData.IDs) == 0 {
+		s.renderError(w, errNoTrashEntriesSpecified)
+		return
+	}
+
+	errorMap := map[string]error{}
+	for _, id := range inputData.IDs {
+		errorMap[id] = s.core.Trash.Remove(id)
+	}
+
+	s.renderBatchParseResult(w, errorMap, multilogger.MSG_MAJOR)
+}
+
 func (s *serverActions) readGameCollection(w http.ResponseWriter, r *http.Request) {
 	var games = s.core.GameTable.AllGames()
 	var output = make([]gamesRenderJSON, 0, len(games))
@@ -193,7 +472,15 @@ func (s *serverActions) readGameCollection(w http.ResponseWriter, r *http.Reques
 		outEntry.Name = info.Name
 		outEntry.Proxy = info.Proxy
 		outEntry.Adapter = info.Adapter
+		outEntry.SteamAppID = info.SteamAppID
+		outEntry.Tags, _ = s.core.GameTags(id)
 		outEntry.Settings, _ = s.core.GameTable.Settings(id)
+		outEntry.Values = typedSettings(outEntry.Settings)
+		outEntry.Schema = s.core.SettingsSchema(id)
+		if install, exists := s.core.Installs.Retrieve(id); exists {
+			outEntry.Installed = install.Installed
+			outEntry.Install = &install
+		}
 
 		output = append(output, outEntry)
 	}
@@ -201,12 +488,503 @@ func (s *serverActions) readGameCollection(w http.ResponseWriter, r *http.Reques
 	s.renderLogResponse(200, "Games read from Game Table successful.", map[string]interface{}{"games": output}, multilogger.MSG_MAJOR, w)
 }
 
+func (s *serverActions) matchGameEntries(w http.ResponseWriter, r *http.Request) {
+	var inputData gameMatchPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	games, err := s.core.MatchGames(inputData.Tags)
+	if err != nil {
+		s.renderError(w, err)
+		return
+	}
+
+	renderResponse(200, "OK.", map[string]interface{}{"games": games}, w)
+}
+
+func (s *serverActions) renderGroups(w http.ResponseWriter, r *http.Request) {
+	renderResponse(200, "OK.", map[string]interface{}{"groups": s.core.Groups()}, w)
+}
+
+func (s *serverActions) getSetting(w http.ResponseWriter, r *http.Request) {
+	var inputData settingPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	v, exists, err := s.core.GameTable.GetSetting(inputData.GameID, inputData.Key)
+	if err == nil && !exists {
+		err = errNoSuchSetting
+	}
+	if err != nil {
+		s.renderError(w, err)
+		return
+	}
+
+	spec := LookupSettingSpec(inputData.Key)
+	typed, err := DecodeSettingValue(spec.Type, v)
+	if err != nil {
+		typed = v
+	}
+
+	renderResponse(200, "OK.", map[string]interface{}{"key": inputData.Key, "value": typed, "spec": spec}, w)
+}
+
+func (s *serverActions) setSetting(w http.ResponseWriter, r *http.Request) {
+	var inputData settingPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if err := s.core.SetSetting(inputData.GameID, inputData.Key, inputData.Value); err != nil {
+		s.renderLogError(w, err)
+		return
+	}
+
+	s.renderLogResponse(200, fmt.Sprintf("Setting %s of %s updated.", inputData.Key, inputData.GameID), map[string]interface{}{"key": inputData.Key, "value": inputData.Value}, multilogger.MSG_MAJOR, w)
+}
+
+func (s *serverActions) removeSetting(w http.ResponseWriter, r *http.Request) {
+	var inputData settingPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if err := s.core.RemoveSetting(inputData.GameID, inputData.Key); err != nil {
+		s.renderLogError(w, err)
+		return
+	}
+
+	s.renderLogResponse(200, fmt.Sprintf("Setting %s of %s removed.", inputData.Key, inputData.GameID), map[string]interface{}{"key": inputData.Key}, multilogger.MSG_MAJOR, w)
+}
+
+func (s *serverActions) readEffectiveSettings(w http.ResponseWriter, r *http.Request) {
+	var inputData settingPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	effective, sources, err := s.core.ResolveSettings(inputData.GameID)
+	if err != nil {
+		s.renderError(w, err)
+		return
+	}
+	own, _ := s.core.GameTable.Settings(inputData.GameID)
+
+	renderResponse(200, "OK.", map[string]interface{}{"effective": typedSettings(effective), "overridden": typedSettings(own), "sources": sources, "schema": s.core.SettingsSchema(inputData.GameID)}, w)
+}
+
+func (s *serverActions) readGlobalSettings(w http.ResponseWriter, r *http.Request) {
+	renderResponse(200, "OK.", map[string]interface{}{"settings": typedSettings(s.core.Globals.All())}, w)
+}
+
+func (s *serverActions) updateGlobalSettings(w http.ResponseWriter, r *http.Request) {
+	var inputData globalSettingsPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if err := s.core.MergeGlobalSettings(inputData.Settings); err != nil {
+		s.renderLogError(w, err)
+		return
+	}
+
+	s.renderLogResponse(200, "Global settings updated.", map[string]interface{}{"settings": typedSettings(s.core.Globals.All())}, multilogger.MSG_MAJOR, w)
+}
+
+func (s *serverActions) exportGameCollection(w http.ResponseWriter, r *http.Request) {
+	gameLists, defaults := s.core.ExportGameCollection()
+
+	renderResponse(200, "OK.", map[string]interface{}{"game_lists": string(gameLists), "default_game_settings": string(defaults)}, w)
+}
+
+func (s *serverActions) importGameCollection(w http.ResponseWriter, r *http.Request) {
+	var inputData gameImportPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	changes, err := s.core.ImportGameCollection([]byte(inputData.GameLists), []byte(inputData.DefaultGameSettings), inputData.DryRun)
+	if err != nil {
+		s.renderLogError(w, err)
+		return
+	}
+
+	if inputData.DryRun {
+		renderResponse(200, "Dry run, nothing was changed.", map[string]interface{}{"changes": changes}, w)
+		return
+	}
+
+	var changed []GameID
+	for _, v := range changes {
+		if v.Error == "" && v.Action != ImportUnchanged {
+			changed = append(changed, v.GameID)
+		}
+	}
+	s.core.DetectNewInstallations(changed, s.core.storedSettingKeys(changed))
+
+	s.renderLogResponse(200, fmt.Sprintf("Imported %d changed games.", len(changed)), map[string]interface{}{"changes": changes}, multilogger.MSG_MAJOR, w)
+}
+
+// requestGameIDs collects the listed IDs and the games of the group. Both are nil if neither is given.
+func (s *serverActions) requestGameIDs(inputData gameEntryEditPost) ([]GameID, error) {
+	var ids []GameID
+	for _, id := range inputData.IDs {
+		ids = append(ids, GameID(id))
+	}
+
+	if inputData.Group != "" {
+		group, err := s.core.GroupGames(inputData.Group)
+		if err != nil {
+			return nil, err
+		}
+		listed := map[GameID]bool{}
+		for _, id := range ids {
+			listed[id] = true
+		}
+		for _, id := range group {
+			if !listed[id] {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+func (s *serverActions) detectInstalledGames(w http.ResponseWriter, r *http.Request) {
+	var inputData gameEntryEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	ids, err := s.requestGameIDs(inputData)
+	if err != nil {
+		s.renderError(w, err)
+		return
+	}
+	if ids == nil {
+		ids = s.core.GameTable.AllGames()
+	}
+
+	errorMap := map[string]error{}
+	for k, v := range s.core.DetectInstallations(ids) {
+		errorMap[k.String()] = v
+	}
+
+	s.renderBatchParseResult(w, errorMap, multilogger.MSG_MAJOR)
+}
+
+func (s *serverActions) refreshServers(w http.ResponseWriter, r *http.Request) {
+	var inputData gameEntryEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	ids, err := s.requestGameIDs(inputData)
+	if err != nil {
+		s.renderLogError(w, err)
+		return
+	}
+	if ids == nil {
+		s.renderLogError(w, errinvalidIDList)
+		return
+	}
+
+	errorMap := map[string]error{}
+	for _, v := range ids {
+		gameID := v
+		id := gameID.String()
+		if !s.core.GameTable.CheckGameEntry(gameID) {
+			errorMap[id] = errUnknownGameID
+			continue
+		}
+
+		s.core.UpdateServerList(gameID, func(servers []ServerData, err error) {
+			if err != nil {
+				s.logs.Add(PrettyLogMessage(500, fmt.Sprintf("Refresh of %s failed: %s", gameID, err.Error()), multilogger.MSG_MAJOR))
+			} else {
+				s.logs.Add(PrettyLogMessage(200, fmt.Sprintf("Refreshed %s: %d servers.", gameID, len(servers)), multilogger.MSG_MINOR))
+			}
+		})
+		errorMap[id] = nil
+	}
+
+	s.renderBatchParseResult(w, errorMap, multilogger.MSG_MINOR)
+}
+
+func (s *serverActions) retrieveServerHistory(w http.ResponseWriter, r *http.Request) (inputData serverQueryPost, samples []ServerSample, ok bool) {
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if !s.core.GameTable.CheckGameEntry(inputData.GameID) {
+		s.renderError(w, errUnknownGameID)
+		return inputData, nil, false
+	}
+
+	samples, exists := s.core.History.Retrieve(inputData.GameID, inputData.Host, inputData.Since)
+	if !exists {
+		s.renderError(w, errNoSuchServer)
+		return inputData, nil, false
+	}
+
+	return inputData, samples, true
+}
+
+func (s *serverActions) renderServerHistory(w http.ResponseWriter, r *http.Request) {
+	inputData, samples, ok := s.retrieveServerHistory(w, r)
+	if !ok {
+		return
+	}
+
+	renderResponse(200, "OK.", map[string]interface{}{"game_id": inputData.GameID, "host": inputData.Host, "samples": samples}, w)
+}
+
+func (s *serverActions) renderServerHistoryCSV(w http.ResponseWriter, r *http.Request) {
+	inputData, samples, ok := s.retrieveServerHistory(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.csv", inputData.GameID, inputData.Host)))
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	WriteHistoryCSV(w, samples)
+}
+
+func (s *serverActions) renderStats(w http.ResponseWriter, r *http.Request) {
+	var inputData gameEntryEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	ids, err := s.requestGameIDs(inputData)
+	if err != nil {
+		s.renderError(w, err)
+		return
+	}
+	if ids == nil {
+		ids = s.core.GameTable.AllGames()
+	}
+
+	games := make(map[GameID]GameStats, len(ids))
+	errorMap := map[string]string{}
+	for _, id := range ids {
+		v, err := s.core.Stats.Retrieve(s.core.GameTable, id)
+		if err != nil {
+			errorMap[id.String()] = err.Error()
+			continue
+		}
+		games[id] = v
+	}
+
+	renderResponse(200, "OK.", map[string]interface{}{"games": games, "summary": SummarizeStats(games), "errors": errorMap}, w)
+}
+
+func (s *serverActions) searchPlayers(w http.ResponseWriter, r *http.Request) {
+	var query PlayerQuery
+	json.Unmarshal([]byte(retrievePostJSON(r)), &query)
+
+	if err := ValidatePlayerQuery(&query); err != nil {
+		s.renderError(w, err)
+		return
+	}
+
+	renderResponse(200, "OK.", map[string]interface{}{"players": s.core.Players.Search(query)}, w)
+}
+
+func (s *serverActions) renderServers(w http.ResponseWriter, r *http.Request) {
+	var inputData serverQueryPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	servers, err := s.core.GameTable.AllServers(inputData.GameID)
+	if err != nil {
+		s.renderError(w, err)
+		return
+	}
+
+	blocked := s.core.Blocks.Blocked(inputData.GameID)
+	output := map[string]interface{}{"servers": servers, "blocked": len(blocked)}
+	if inputData.ShowBlocked {
+		output["blocked_servers"] = blocked
+	}
+
+	renderResponse(200, "OK.", output, w)
+}
+
+func (s *serverActions) readBlockRules(w http.ResponseWriter, r *http.Request) {
+	var inputData blockListEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if !s.core.GameTable.CheckGameEntry(inputData.GameID) {
+		s.renderError(w, errUnknownGameID)
+		return
+	}
+
+	renderResponse(200, "OK.", map[string]interface{}{"rules": s.core.Blocks.Rules(inputData.GameID), "blocked": len(s.core.Blocks.Blocked(inputData.GameID))}, w)
+}
+
+func (s *serverActions) updateBlockRules(w http.ResponseWriter, r *http.Request) {
+	var inputData blockListEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if !s.core.GameTable.CheckGameEntry(inputData.GameID) {
+		s.renderError(w, errUnknownGameID)
+		return
+	}
+
+	if err := s.core.Blocks.SetRules(inputData.GameID, inputData.Rules); err != nil {
+		s.renderLogError(w, err)
+		return
+	}
+
+	s.renderLogResponse(200, fmt.Sprintf("Block rules of %s updated.", inputData.GameID), map[string]interface{}{"rules": inputData.Rules}, multilogger.MSG_MAJOR, w)
+}
+
+func (s *serverActions) upsertFavorites(w http.ResponseWriter, r *http.Request) {
+	var inputData favoriteEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if !s.core.GameTable.CheckGameEntry(inputData.GameID) {
+		s.renderError(w, errUnknownGameID)
+		return
+	}
+	if len(inputData.Favorites) == 0 {
+		s.renderError(w, errNoFavoritesSpecified)
+		return
+	}
+
+	errorMap := map[string]error{}
+	for _, f := range inputData.Favorites {
+		errorMap[f.Host] = s.core.SetFavorite(inputData.GameID, f)
+	}
+
+	s.renderBatchParseResult(w, errorMap, multilogger.MSG_MAJOR)
+}
+
+func (s *serverActions) readFavorites(w http.ResponseWriter, r *http.Request) {
+	var inputData favoriteEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	favorites, err := s.core.GameTable.Favorites(inputData.GameID)
+	if err != nil {
+		s.renderError(w, err)
+		return
+	}
+
+	renderResponse(200, "OK.", map[string]interface{}{"favorites": favorites}, w)
+}
+
+func (s *serverActions) deleteFavorites(w http.ResponseWriter, r *http.Request) {
+	var inputData favoriteEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if !s.core.GameTable.CheckGameEntry(inputData.GameID) {
+		s.renderError(w, errUnknownGameID)
+		return
+	}
+	if len(inputData.Hosts) == 0 {
+		s.renderError(w, errNoFavoritesSpecified)
+		return
+	}
+
+	errorMap := map[string]error{}
+	for _, host := range inputData.Hosts {
+		errorMap[host] = s.core.RemoveFavorite(inputData.GameID, host)
+	}
+
+	s.renderBatchParseResult(w, errorMap, multilogger.MSG_MAJOR)
+}
+
+func (s *serverActions) upsertBuddies(w http.ResponseWriter, r *http.Request) {
+	var inputData buddyEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if len(inputData.Buddies) == 0 {
+		s.renderError(w, errNoBuddiesSpecified)
+		return
+	}
+
+	errorMap := map[string]error{}
+	for _, b := range inputData.Buddies {
+		errorMap[b.ID] = s.core.Buddies.Upsert(b)
+	}
+
+	s.renderBatchParseResult(w, errorMap, multilogger.MSG_MAJOR)
+}
+
+func (s *serverActions) readBuddies(w http.ResponseWriter, r *http.Request) {
+	renderResponse(200, "OK.", map[string]interface{}{"buddies": s.core.Buddies.All(), "webhooks": s.core.Buddies.Webhooks()}, w)
+}
+
+func (s *serverActions) deleteBuddies(w http.ResponseWriter, r *http.Request) {
+	var inputData buddyEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if inputData.IDs == nil {
+		s.renderLogError(w, errinvalidIDList)
+		return
+	}
+
+	errorMap := map[string]error{}
+	for _, id := range inputData.IDs {
+		errorMap[id] = s.core.Buddies.Remove(id)
+	}
+
+	s.renderBatchParseResult(w, errorMap, multilogger.MSG_MAJOR)
+}
+
+func (s *serverActions) renderBuddyPresence(w http.ResponseWriter, r *http.Request) {
+	renderResponse(200, "OK.", map[string]interface{}{"presence": s.core.Buddies.Presence()}, w)
+}
+
+func (s *serverActions) setBuddyWebhooks(w http.ResponseWriter, r *http.Request) {
+	var inputData buddyEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if err := s.core.Buddies.SetWebhooks(inputData.Webhooks); err != nil {
+		s.renderLogError(w, err)
+		return
+	}
+
+	s.renderLogResponse(200, "Buddy webhooks updated.", map[string]interface{}{"webhooks": inputData.Webhooks}, multilogger.MSG_MAJOR, w)
+}
+
+func (s *serverActions) upsertAlertRules(w http.ResponseWriter, r *http.Request) {
+	var inputData alertEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if len(inputData.Rules) == 0 {
+		s.renderError(w, errNoAlertRulesSpecified)
+		return
+	}
+
+	errorMap := map[string]error{}
+	for _, rule := range inputData.Rules {
+		errorMap[rule.ID] = s.core.Alerts.Upsert(rule)
+	}
+
+	s.renderBatchParseResult(w, errorMap, multilogger.MSG_MAJOR)
+}
+
+func (s *serverActions) readAlertRules(w http.ResponseWriter, r *http.Request) {
+	renderResponse(200, "OK.", map[string]interface{}{"rules": s.core.Alerts.All()}, w)
+}
+
+func (s *serverActions) deleteAlertRules(w http.ResponseWriter, r *http.Request) {
+	var inputData alertEditPost
+	json.Unmarshal([]byte(retrievePostJSON(r)), &inputData)
+
+	if inputData.IDs == nil {
+		s.renderLogError(w, errinvalidIDList)
+		return
+	}
+
+	errorMap := map[string]error{}
+	for _, id := range inputData.IDs {
+		errorMap[id] = s.core.Alerts.Remove(id)
+	}
+
+	s.renderBatchParseResult(w, errorMap, multilogger.MSG_MAJOR)
+}
+
 func (s *serverActions) cleanup() {
+	if s.catalogWatcher != nil {
+		s.catalogWatcher.Stop()
+	}
+	if s.snapshots != nil {
+		s.snapshots.Stop()
+		s.logSnapshotSave(SaveSnapshot(s.core.GameTable, s.snapshots.path))
+	}
 	s.logs.Close()
 }
 
-func makeActionInstance(password string) *serverActions {
-	return &serverActions{password: password, logs: multilogger.MakeLogCollection(multilogger.LoggingModes{Mem: true}, nil), core: StartCore()}
+func makeActionInstance(password string, gameTable GameTable) *serverActions {
+	s := &serverActions{password: password, logs: multilogger.MakeLogCollection(multilogger.LoggingModes{Mem: true}, nil), core: StartCore(gameTable), catalogReload: semaphore.MakeSemaphore(1)}
+	s.core.Buddies.OnEvents = s.notifyBuddyEvents
+	s.core.Alerts.OnEvents = s.notifyAlertEvents
+
+	return s
 }
 
 func makeServeMux(actions *serverActions, exitChan chan struct{}) *http.ServeMux {
@@ -217,6 +995,9 @@ func makeServeMux(actions *serverActions, exitChan chan struct{}) *http.ServeMux
 		close(exitChan)
 	})
 	sMux.HandleFunc(systemPrefix+"/ping", actions.renderPing)
+	sMux.HandleFunc(systemPrefix+"/registries", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.renderRegistries)
+	})
 	sMux.HandleFunc(systemPrefix+"/logs", func(w http.ResponseWriter, r *http.Request) { actions.checkRequestPassword(w, r, actions.renderLogs) })
 	sMux.HandleFunc(gameCollPrefix+"/create", func(w http.ResponseWriter, r *http.Request) {
 		actions.checkRequestPassword(w, r, actions.createGameEntries)
@@ -230,6 +1011,111 @@ func makeServeMux(actions *serverActions, exitChan chan struct{}) *http.ServeMux
 	sMux.HandleFunc(gameCollPrefix+"/delete", func(w http.ResponseWriter, r *http.Request) {
 		actions.checkRequestPassword(w, r, actions.deleteGameEntry)
 	})
+	sMux.HandleFunc(trashPrefix+"/read", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.readTrash)
+	})
+	sMux.HandleFunc(trashPrefix+"/restore", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.restoreTrash)
+	})
+	sMux.HandleFunc(trashPrefix+"/purge", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.purgeTrash)
+	})
+	sMux.HandleFunc(gameCollPrefix+"/clone", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.cloneGameEntry)
+	})
+	sMux.HandleFunc(gameCollPrefix+"/rename", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.renameGameEntry)
+	})
+	sMux.HandleFunc(gameCollPrefix+"/match", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.matchGameEntries)
+	})
+	sMux.HandleFunc(gameCollPrefix+"/groups", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.renderGroups)
+	})
+	sMux.HandleFunc(gameCollPrefix+"/setting/get", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.getSetting)
+	})
+	sMux.HandleFunc(gameCollPrefix+"/setting/set", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.setSetting)
+	})
+	sMux.HandleFunc(gameCollPrefix+"/setting/remove", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.removeSetting)
+	})
+	sMux.HandleFunc(gameCollPrefix+"/setting/effective", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.readEffectiveSettings)
+	})
+	sMux.HandleFunc(settingsPrefix+"/global/read", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.readGlobalSettings)
+	})
+	sMux.HandleFunc(settingsPrefix+"/global/update", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.updateGlobalSettings)
+	})
+	sMux.HandleFunc(gameCollPrefix+"/export", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.exportGameCollection)
+	})
+	sMux.HandleFunc(gameCollPrefix+"/import", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.importGameCollection)
+	})
+	sMux.HandleFunc(gameCollPrefix+"/detect", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.detectInstalledGames)
+	})
+	sMux.HandleFunc(serversPrefix+"/refresh", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.refreshServers)
+	})
+	sMux.HandleFunc(serversPrefix+"/stats", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.renderStats)
+	})
+	sMux.HandleFunc(serversPrefix+"/players", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.searchPlayers)
+	})
+	sMux.HandleFunc(serversPrefix+"/history", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.renderServerHistory)
+	})
+	sMux.HandleFunc(serversPrefix+"/history.csv", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.renderServerHistoryCSV)
+	})
+	sMux.HandleFunc(serversPrefix+"/read", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.renderServers)
+	})
+	sMux.HandleFunc(blockListPrefix+"/read", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.readBlockRules)
+	})
+	sMux.HandleFunc(blockListPrefix+"/update", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.updateBlockRules)
+	})
+	sMux.HandleFunc(favoritesPrefix+"/create", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.upsertFavorites)
+	})
+	sMux.HandleFunc(favoritesPrefix+"/read", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.readFavorites)
+	})
+	sMux.HandleFunc(favoritesPrefix+"/delete", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.deleteFavorites)
+	})
+	sMux.HandleFunc(buddiesPrefix+"/create", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.upsertBuddies)
+	})
+	sMux.HandleFunc(buddiesPrefix+"/read", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.readBuddies)
+	})
+	sMux.HandleFunc(buddiesPrefix+"/delete", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.deleteBuddies)
+	})
+	sMux.HandleFunc(buddiesPrefix+"/presence", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.renderBuddyPresence)
+	})
+	sMux.HandleFunc(buddiesPrefix+"/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.setBuddyWebhooks)
+	})
+	sMux.HandleFunc(alertsPrefix+"/create", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.upsertAlertRules)
+	})
+	sMux.HandleFunc(alertsPrefix+"/read", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.readAlertRules)
+	})
+	sMux.HandleFunc(alertsPrefix+"/delete", func(w http.ResponseWriter, r *http.Request) {
+		actions.checkRequestPassword(w, r, actions.deleteAlertRules)
+	})
 
 	return sMux
 }
@@ -1,22 +1,72 @@
 package main
 
-import "github.com/skybon/semaphore"
+import (
+	"sort"
+
+	"github.com/skybon/semaphore"
+)
 
 type AdapterID string
 
 type AdaptFunc func([]string, GameInfo, SettingsMap) ([]ServerData, error)
 
+// AdapterInfo describes what an adapter consumes. Proxies are the proxies whose output it parses, Formats name that output.
+type AdapterInfo struct {
+	ID          AdapterID `json:"id"`
+	Description string    `json:"description"`
+	Settings    []string  `json:"settings"`
+	Proxies     []ProxyID `json:"proxies"`
+	Formats     []string  `json:"formats"`
+}
+
+// AcceptsProxy tells whether the adapter parses the proxy's output. Adapters that list no proxies accept any.
+func (info AdapterInfo) AcceptsProxy(proxy ProxyID) bool {
+	if len(info.Proxies) == 0 {
+		return true
+	}
+	for _, v := range info.Proxies {
+		if v == proxy {
+			return true
+		}
+	}
+
+	return false
+}
+
 type AdapterCollection struct {
 	data      map[AdapterID]AdaptFunc
+	info      map[AdapterID]AdapterInfo
 	semaphore semaphore.Semaphore
 }
 
+// All describes every registered adapter, ordered by ID. Adapters without a description only have their ID set.
+func (c *AdapterCollection) All() (output []AdapterInfo) {
+	c.semaphore.Exec(func() {
+		output = make([]AdapterInfo, 0, len(c.data))
+		for k := range c.data {
+			info := c.info[k]
+			info.ID = k
+			output = append(output, info)
+		}
+	})
+	sort.Slice(output, func(i, j int) bool { return output[i].ID < output[j].ID })
+
+	return output
+}
+
 func (c *AdapterCollection) Insert(k AdapterID, v AdaptFunc) {
 	c.semaphore.Exec(func() {
 		c.data[k] = v
 	})
 }
 
+// Describe sets the description of the adapter.
+func (c *AdapterCollection) Describe(k AdapterID, info AdapterInfo) {
+	c.semaphore.Exec(func() {
+		c.info[k] = info
+	})
+}
+
 func (c *AdapterCollection) Retrieve(k AdapterID) (v AdaptFunc, exists bool) {
 	c.semaphore.Exec(func() {
 		v, exists = c.data[k]
@@ -25,6 +75,17 @@ func (c *AdapterCollection) Retrieve(k AdapterID) (v AdaptFunc, exists bool) {
 	return v, exists
 }
 
+// Info returns the description of a registered adapter.
+func (c *AdapterCollection) Info(k AdapterID) (info AdapterInfo, exists bool) {
+	c.semaphore.Exec(func() {
+		_, exists = c.data[k]
+		info = c.info[k]
+	})
+	info.ID = k
+
+	return info, exists
+}
+
 func MakeAdapterCollection() *AdapterCollection {
-	return &AdapterCollection{data: make(map[AdapterID]AdaptFunc), semaphore: semaphore.MakeSemaphore(1)}
+	return &AdapterCollection{data: make(map[AdapterID]AdaptFunc), info: make(map[AdapterID]AdapterInfo), semaphore: semaphore.MakeSemaphore(1)}
 }
@@ -6,6 +6,13 @@ import (
 	"encoding/xml"
 )
 
+// QStatXMLInfo describes the qstat_xml adapter.
+var QStatXMLInfo = AdapterInfo{
+	Description: "Parses QStat XML output into servers, rules and players",
+	Proxies:     []ProxyID{ProxyQStatOutput},
+	Formats:     []string{"qstat-xml"},
+}
+
 type qStatServerRule struct {
 	Name  *string `xml:"name,attr"`
 	Value *string `xml:",innerxml"`
@@ -102,6 +109,9 @@ func (e qStatServer) ToServerData() (ServerData, error) {
 	newEntry.Map = *e.Map
 	newEntry.NumPlayers = *e.NumPlayers
 	newEntry.MaxPlayers = *e.MaxPlayers
+	if e.GameType != nil {
+		newEntry.GameType = *e.GameType
+	}
 
 	qRules := e.Rules
 	if qRules != nil {
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/skybon/semaphore"
+)
+
+// AlertRule fires when a server of the game starts matching the condition. Empty GameID means every game.
+// Cooldown, in seconds, is the minimum time between two alerts of the rule for the same server.
+type AlertRule struct {
+	ID        string   `json:"id"`
+	GameID    GameID   `json:"game_id"`
+	Condition string   `json:"condition"`
+	Cooldown  int64    `json:"cooldown"`
+	Webhooks  []string `json:"webhooks"`
+}
+
+func (r AlertRule) validate() (*Condition, error) {
+	if r.ID == "" {
+		return nil, errInvalidID
+	}
+	if r.Cooldown < 0 {
+		return nil, errInvalidCooldown
+	}
+
+	return CompileCondition(r.Condition)
+}
+
+type AlertEvent struct {
+	Time   time.Time     `json:"time"`
+	Rule   AlertRule     `json:"rule"`
+	GameID GameID        `json:"game_id"`
+	Server ServerSummary `json:"server"`
+}
+
+type alertRuleState struct {
+	rule      AlertRule
+	condition *Condition
+	// matching holds hosts that matched on the last refresh, keyed by game.
+	matching map[GameID]map[string]bool
+	fired    map[GameID]map[string]time.Time
+}
+
+type alertRulesDump struct {
+	Rules []AlertRule `json:"rules"`
+}
+
+// AlertCollection keeps alert rules and their trigger state. OnEvents receives every non-empty batch of fired alerts.
+type AlertCollection struct {
+	OnEvents func([]AlertEvent)
+
+	path      string
+	rules     map[string]*alertRuleState
+	semaphore semaphore.Semaphore
+}
+
+func (c *AlertCollection) all() []AlertRule {
+	output := make([]AlertRule, 0, len(c.rules))
+	for _, v := range c.rules {
+		output = append(output, v.rule)
+	}
+	sort.Slice(output, func(i, j int) bool { return output[i].ID < output[j].ID })
+
+	return output
+}
+
+func (c *AlertCollection) save() error {
+	return saveJSONFile(c.path, alertRulesDump{Rules: c.all()})
+}
+
+func (c *AlertCollection) All() (output []AlertRule) {
+	c.semaphore.Exec(func() { output = c.all() })
+
+	return output
+}
+
+func (c *AlertCollection) upsert(r AlertRule) error {
+	condition, err := r.validate()
+	if err != nil {
+		return err
+	}
+
+	state, exists := c.rules[r.ID]
+	if !exists || state.rule.Condition != r.Condition || state.rule.GameID != r.GameID {
+		state = &alertRuleState{matching: map[GameID]map[string]bool{}, fired: map[GameID]map[string]time.Time{}}
+		c.rules[r.ID] = state
+	}
+	state.rule = r
+	state.condition = condition
+
+	return nil
+}
+
+// Upsert adds the rule or replaces the one with the same ID. Changing the condition or the game resets the trigger state.
+func (c *AlertCollection) Upsert(r AlertRule) (err error) {
+	c.semaphore.Exec(func() {
+		if err = c.upsert(r); err == nil {
+			err = c.save()
+		}
+	})
+
+	return err
+}
+
+func (c *AlertCollection) Remove(id string) (err error) {
+	c.semaphore.Exec(func() {
+		if _, exists := c.rules[id]; !exists {
+			err = errNoSuchAlertRule
+			return
+		}
+		delete(c.rules, id)
+		err = c.save()
+	})
+
+	return err
+}
+
+// Evaluate checks the game's refreshed servers against the rules. A rule fires for a server when it starts matching, unless the rule has fired for it within the cooldown.
+func (c *AlertCollection) Evaluate(gameID GameID, servers []ServerData, t time.Time) (events []AlertEvent) {
+	c.semaphore.Exec(func() {
+		for _, rule := range c.all() {
+			if rule.GameID != "" && rule.GameID != gameID {
+				continue
+			}
+			state := c.rules[rule.ID]
+			previous := state.matching[gameID]
+			fired := state.fired[gameID]
+			if fired == nil {
+				fired = map[string]time.Time{}
+				state.fired[gameID] = fired
+			}
+
+			matching := map[string]bool{}
+			for _, v := range servers {
+				if !state.condition.Match(v) {
+					continue
+				}
+				matching[v.Host] = true
+				if previous[v.Host] {
+					continue
+				}
+				if last, exists := fired[v.Host]; exists && t.Sub(last) < time.Duration(rule.Cooldown)*time.Second {
+					continue
+				}
+				fired[v.Host] = t
+				events = append(events, AlertEvent{Time: t, Rule: rule, GameID: gameID, Server: ServerSummary{v.Host, v.Name, v.Map, v.NumPlayers, v.MaxPlayers}})
+			}
+			state.matching[gameID] = matching
+		}
+	})
+
+	if len(events) > 0 && c.OnEvents != nil {
+		c.OnEvents(events)
+	}
+
+	return events
+}
+
+// RemoveGame forgets the trigger state of the game's servers.
+func (c *AlertCollection) RemoveGame(gameID GameID) {
+	c.semaphore.Exec(func() {
+		for _, state := range c.rules {
+			delete(state.matching, gameID)
+			delete(state.fired, gameID)
+		}
+	})
+}
+
+// RenameGame points the rules of src at dst and moves the trigger state of its servers.
+func (c *AlertCollection) RenameGame(src GameID, dst GameID) (err error) {
+	c.semaphore.Exec(func() {
+		changed := false
+		for _, state := range c.rules {
+			if state.rule.GameID == src {
+				state.rule.GameID = dst
+				changed = true
+			}
+			if v, exists := state.matching[src]; exists {
+				state.matching[dst] = v
+				delete(state.matching, src)
+			}
+			if v, exists := state.fired[src]; exists {
+				state.fired[dst] = v
+				delete(state.fired, src)
+			}
+		}
+		if changed {
+			err = c.save()
+		}
+	})
+
+	return err
+}
+
+// LoadAlertCollection reads alert rules from path. Empty path keeps the rules in memory only.
+func LoadAlertCollection(path string) (*AlertCollection, error) {
+	c := &AlertCollection{path: path, rules: map[string]*alertRuleState{}, semaphore: semaphore.MakeSemaphore(1)}
+
+	var dump alertRulesDump
+	if err := loadJSONFile(path, &dump); err != nil {
+		return nil, err
+	}
+	for _, r := range dump.Rules {
+		if err := c.upsert(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// MakeAlertCollection creates an empty in-memory alert collection.
+func MakeAlertCollection() *AlertCollection {
+	c, _ := LoadAlertCollection("")
+	return c
+}
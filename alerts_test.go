@@ -0,0 +1,80 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skybon/goutil"
+)
+
+func alertEventHosts(events []AlertEvent) []string {
+	output := []string{}
+	for _, e := range events {
+		output = append(output, e.Rule.ID+":"+e.Server.Host)
+	}
+	return output
+}
+
+func TestAlertCollectionEvaluate(t *testing.T) {
+	c := MakeAlertCollection()
+	if err := c.Upsert(AlertRule{ID: "match", GameID: "q3a", Condition: `host == "a:1" && numplayers >= 6`, Cooldown: 600}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Upsert(AlertRule{ID: "dm17", Condition: `map == "q3dm17"`}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Upsert(AlertRule{ID: "broken", Condition: `map ==`}); err == nil {
+		t.Error("expected condition error")
+	}
+
+	t0 := time.Unix(1000, 0)
+	fixtures := []struct {
+		GameID  GameID
+		Servers []ServerData
+		Offset  time.Duration
+		Result  []string
+	}{
+		{"q3a", []ServerData{{Host: "a:1", NumPlayers: 6, Map: "q3dm17"}, {Host: "b:1", Map: "q3dm6"}}, 0, []string{"dm17:a:1", "match:a:1"}},
+		{"q3a", []ServerData{{Host: "a:1", NumPlayers: 8, Map: "q3dm17"}, {Host: "b:1", Map: "q3dm17"}}, time.Minute, []string{"dm17:b:1"}},
+		{"q3a", []ServerData{{Host: "a:1", NumPlayers: 2, Map: "q3dm6"}, {Host: "b:1", Map: "q3dm17"}}, 2 * time.Minute, []string{}},
+		// The match rule is re-armed but still cools down, the dm17 rule has no cooldown.
+		{"q3a", []ServerData{{Host: "a:1", NumPlayers: 6, Map: "q3dm17"}, {Host: "b:1", Map: "q3dm17"}}, 3 * time.Minute, []string{"dm17:a:1"}},
+		{"q3a", []ServerData{{Host: "a:1", NumPlayers: 0, Map: "q3dm6"}}, 4 * time.Minute, []string{}},
+		{"q3a", []ServerData{{Host: "a:1", NumPlayers: 6, Map: "q3dm6"}}, 11 * time.Minute, []string{"match:a:1"}},
+		{"openarena", []ServerData{{Host: "a:1", NumPlayers: 6, Map: "q3dm17"}}, 12 * time.Minute, []string{"dm17:a:1"}},
+	}
+
+	for _, fixture := range fixtures {
+		result := alertEventHosts(c.Evaluate(fixture.GameID, fixture.Servers, t0.Add(fixture.Offset)))
+		if !reflect.DeepEqual(fixture.Result, result) {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture.Result, result))
+		}
+	}
+}
+
+func TestAlertCollectionPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+
+	c, err := LoadAlertCollection(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Upsert(AlertRule{ID: "match", GameID: "q3a", Condition: `numplayers >= 6`, Cooldown: 60, Webhooks: []string{"http://localhost/hook"}})
+	c.Upsert(AlertRule{ID: "dm17", Condition: `map == "q3dm17"`})
+	if err := c.Remove("dm17"); err != nil {
+		t.Error(err)
+	}
+	if err := c.Remove("dm17"); err != errNoSuchAlertRule {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errNoSuchAlertRule.Error(), err))
+	}
+
+	restored, err := LoadAlertCollection(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.All(), restored.All()) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, c.All(), restored.All()))
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"net"
+	"strings"
+
+	"github.com/skybon/semaphore"
+)
+
+// Reasons a server is hidden for.
+const (
+	BlockReasonAddress   = "address"
+	BlockReasonName      = "name"
+	BlockReasonOverMax   = "over_max_players"
+	BlockReasonZeroPing  = "zero_ping_players"
+	BlockReasonNameFlood = "name_flood"
+)
+
+// BlockRules hide a game's servers. Addresses are IPs, hostnames or CIDR ranges, names are wildcard patterns compared without color codes and case.
+// The heuristics hide servers reporting more players than slots, servers whose players all have zero ping and groups of at least NameFlood servers sharing one IP and name.
+type BlockRules struct {
+	Addresses []string `json:"addresses"`
+	Names     []string `json:"names"`
+	OverMax   bool     `json:"over_max_players"`
+	ZeroPing  bool     `json:"zero_ping_players"`
+	NameFlood int      `json:"name_flood"`
+}
+
+// IsEmpty tells whether the rules hide nothing.
+func (r BlockRules) IsEmpty() bool {
+	return len(r.Addresses) == 0 && len(r.Names) == 0 && !r.OverMax && !r.ZeroPing && r.NameFlood == 0
+}
+
+// Validate checks address and pattern syntax.
+func (r BlockRules) Validate() error {
+	for _, v := range r.Addresses {
+		if strings.Contains(v, "/") {
+			if _, _, err := net.ParseCIDR(v); err != nil {
+				return errInvalidBlockRule
+			}
+		} else if v == "" {
+			return errInvalidBlockRule
+		}
+	}
+	for _, v := range r.Names {
+		if _, err := MatchWildcard(v, ""); err != nil || v == "" {
+			return errInvalidBlockRule
+		}
+	}
+	if r.NameFlood < 0 {
+		return errInvalidBlockRule
+	}
+
+	return nil
+}
+
+// BlockedServer is a server hidden by the block rules.
+type BlockedServer struct {
+	Server ServerData `json:"server"`
+	Reason string     `json:"reason"`
+}
+
+type compiledBlockRules struct {
+	rules     BlockRules
+	addresses map[string]bool
+	networks  []*net.IPNet
+	names     []string
+}
+
+func compileBlockRules(r BlockRules) *compiledBlockRules {
+	output := &compiledBlockRules{rules: r, addresses: map[string]bool{}}
+	for _, v := range r.Addresses {
+		if _, network, err := net.ParseCIDR(v); err == nil {
+			output.networks = append(output.networks, network)
+		} else {
+			output.addresses[strings.ToLower(v)] = true
+		}
+	}
+	for _, v := range r.Names {
+		output.names = append(output.names, cleanPlayerName(v))
+	}
+
+	return output
+}
+
+func serverAddress(v ServerData) string {
+	host, _, _, err := ParseHostPort(v.Host)
+	if err != nil || host == "" {
+		return v.Host
+	}
+
+	return strings.ToLower(host)
+}
+
+func (r *compiledBlockRules) reason(v ServerData, floods map[string]int) string {
+	address := serverAddress(v)
+	if r.addresses[address] {
+		return BlockReasonAddress
+	}
+	if ip := net.ParseIP(address); ip != nil {
+		for _, network := range r.networks {
+			if network.Contains(ip) {
+				return BlockReasonAddress
+			}
+		}
+	}
+
+	name := cleanPlayerName(v.Name)
+	for _, pattern := range r.names {
+		if matched, _ := MatchWildcard(pattern, name); matched {
+			return BlockReasonName
+		}
+	}
+
+	if r.rules.OverMax && v.MaxPlayers > 0 && (v.NumPlayers > v.MaxPlayers || len(v.Players) > v.MaxPlayers) {
+		return BlockReasonOverMax
+	}
+
+	if r.rules.ZeroPing && len(v.Players) > 0 {
+		zero := true
+		for _, p := range v.Players {
+			if p.Info["ping"] != "0" {
+				zero = false
+				break
+			}
+		}
+		if zero {
+			return BlockReasonZeroPing
+		}
+	}
+
+	if r.rules.NameFlood > 0 && floods[address+"\x00"+name] >= r.rules.NameFlood {
+		return BlockReasonNameFlood
+	}
+
+	return ""
+}
+
+// BlockList keeps per-game block rules and the servers they hid on the last refresh.
+type BlockList struct {
+	path      string
+	rules     map[GameID]*compiledBlockRules
+	blocked   map[GameID][]BlockedServer
+	semaphore semaphore.Semaphore
+}
+
+func (c *BlockList) save() error {
+	dump := make(map[GameID]BlockRules, len(c.rules))
+	for k, v := range c.rules {
+		dump[k] = v.rules
+	}
+
+	return saveJSONFile(c.path, dump)
+}
+
+// Rules returns the game's block rules.
+func (c *BlockList) Rules(gameID GameID) (output BlockRules) {
+	c.semaphore.Exec(func() {
+		if r, exists := c.rules[gameID]; exists {
+			output = r.rules
+		}
+	})
+
+	return output
+}
+
+// SetRules replaces the game's block rules. They apply starting with the next refresh.
+func (c *BlockList) SetRules(gameID GameID, r BlockRules) (err error) {
+	if err = r.Validate(); err != nil {
+		return err
+	}
+
+	c.semaphore.Exec(func() {
+		c.rules[gameID] = compileBlockRules(r)
+		err = c.save()
+	})
+
+	return err
+}
+
+// Blocked returns the servers hidden on the game's last refresh.
+func (c *BlockList) Blocked(gameID GameID) (output []BlockedServer) {
+	c.semaphore.Exec(func() { output = append([]BlockedServer{}, c.blocked[gameID]...) })
+
+	return output
+}
+
+// Apply splits the query result into visible servers and blocked ones, which are remembered until the next refresh. Exempt servers are never blocked.
+func (c *BlockList) Apply(gameID GameID, servers []ServerData, exempt func(ServerData) bool) ([]ServerData, []BlockedServer) {
+	var rules *compiledBlockRules
+	c.semaphore.Exec(func() { rules = c.rules[gameID] })
+
+	visible := make([]ServerData, 0, len(servers))
+	blocked := []BlockedServer{}
+
+	if rules == nil {
+		visible = append(visible, servers...)
+	} else {
+		floods := map[string]int{}
+		if rules.rules.NameFlood > 0 {
+			for _, v := range servers {
+				floods[serverAddress(v)+"\x00"+cleanPlayerName(v.Name)]++
+			}
+		}
+
+		for _, v := range servers {
+			reason := rules.reason(v, floods)
+			if reason == "" || (exempt != nil && exempt(v)) {
+				visible = append(visible, v)
+			} else {
+				blocked = append(blocked, BlockedServer{Server: v, Reason: reason})
+			}
+		}
+	}
+
+	c.semaphore.Exec(func() { c.blocked[gameID] = blocked })
+
+	return visible, blocked
+}
+
+// CopyGame gives dst the block rules of src.
+func (c *BlockList) CopyGame(src GameID, dst GameID) (err error) {
+	c.semaphore.Exec(func() {
+		if r, exists := c.rules[src]; exists {
+			c.rules[dst] = r
+			err = c.save()
+		}
+	})
+
+	return err
+}
+
+// RenameGame moves the rules and blocked servers of src to dst.
+func (c *BlockList) RenameGame(src GameID, dst GameID) (err error) {
+	c.semaphore.Exec(func() {
+		if blocked, exists := c.blocked[src]; exists {
+			c.blocked[dst] = blocked
+			delete(c.blocked, src)
+		}
+		if r, exists := c.rules[src]; exists {
+			c.rules[dst] = r
+			delete(c.rules, src)
+			err = c.save()
+		}
+	})
+
+	return err
+}
+
+// RemoveGame forgets the game's rules and blocked servers.
+func (c *BlockList) RemoveGame(gameID GameID) (err error) {
+	c.semaphore.Exec(func() {
+		delete(c.blocked, gameID)
+		if _, exists := c.rules[gameID]; exists {
+			delete(c.rules, gameID)
+			err = c.save()
+		}
+	})
+
+	return err
+}
+
+// LoadBlockList reads block rules from path. Empty path keeps the rules in memory only.
+func LoadBlockList(path string) (*BlockList, error) {
+	c := &BlockList{path: path, rules: map[GameID]*compiledBlockRules{}, blocked: map[GameID][]BlockedServer{}, semaphore: semaphore.MakeSemaphore(1)}
+
+	dump := map[GameID]BlockRules{}
+	if err := loadJSONFile(path, &dump); err != nil {
+		return nil, err
+	}
+	for k, v := range dump {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+		c.rules[k] = compileBlockRules(v)
+	}
+
+	return c, nil
+}
+
+// MakeBlockList creates an empty in-memory block list.
+func MakeBlockList() *BlockList {
+	c, _ := LoadBlockList("")
+	return c
+}
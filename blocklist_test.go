@@ -0,0 +1,84 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestBlockListApply(t *testing.T) {
+	c := MakeBlockList()
+	rules := BlockRules{Addresses: []string{"1.2.3.4", "10.0.0.0/8"}, Names: []string{"*free vip*"}, OverMax: true, ZeroPing: true, NameFlood: 3}
+	if err := c.SetRules("csgo", rules); err != nil {
+		t.Fatal(err)
+	}
+
+	servers := []ServerData{
+		{Host: "1.2.3.4:27015", Name: "Blocked by IP"},
+		{Host: "10.1.2.3:27015", Name: "Blocked by range"},
+		{Host: "5.5.5.5:27015", Name: "^1FREE VIP^7 today at example.com/vip"},
+		{Host: "6.6.6.6:27015", Name: "Inflated", NumPlayers: 64, MaxPlayers: 32},
+		{Host: "7.7.7.7:27015", Name: "Bots", Players: []PlayerData{makeTestPlayer("a", "0", "0"), makeTestPlayer("b", "0", "0")}},
+		{Host: "8.8.8.8:27015", Name: "Spam"},
+		{Host: "8.8.8.8:27016", Name: "spam"},
+		{Host: "8.8.8.8:27017", Name: "Spam"},
+		{Host: "9.9.9.9:27015", Name: "Spam"},
+		{Host: "9.9.9.9:27016", Name: "Fair", NumPlayers: 2, MaxPlayers: 32, Players: []PlayerData{makeTestPlayer("a", "0", "0"), makeTestPlayer("b", "0", "40")}},
+		{Host: "10.0.0.5:27015", Name: "LAN favorite"},
+	}
+
+	visible, blocked := c.Apply("csgo", servers, func(v ServerData) bool { return v.Host == "10.0.0.5:27015" })
+
+	visibleFixture := []ServerData{servers[8], servers[9], servers[10]}
+	if !reflect.DeepEqual(visibleFixture, visible) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, visibleFixture, visible))
+	}
+
+	reasons := []string{}
+	for _, v := range blocked {
+		reasons = append(reasons, v.Reason)
+	}
+	reasonsFixture := []string{BlockReasonAddress, BlockReasonAddress, BlockReasonName, BlockReasonOverMax, BlockReasonZeroPing, BlockReasonNameFlood, BlockReasonNameFlood, BlockReasonNameFlood}
+	if !reflect.DeepEqual(reasonsFixture, reasons) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, reasonsFixture, reasons))
+	}
+	if result := len(c.Blocked("csgo")); result != len(reasonsFixture) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, len(reasonsFixture), result))
+	}
+
+	if visible, blocked := c.Apply("q3a", servers, nil); len(visible) != len(servers) || len(blocked) != 0 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, len(servers), len(visible)))
+	}
+
+	for _, invalid := range []BlockRules{{Addresses: []string{"10.0.0.0/99"}}, {Names: []string{"[bad"}}, {NameFlood: -1}} {
+		if err := c.SetRules("csgo", invalid); err != errInvalidBlockRule {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errInvalidBlockRule.Error(), err))
+		}
+	}
+}
+
+func TestBlockListPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.json")
+
+	c, err := LoadBlockList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := BlockRules{Addresses: []string{"10.0.0.0/8"}, Names: []string{"spam*"}, OverMax: true}
+	c.SetRules("csgo", rules)
+	c.SetRules("q3a", BlockRules{ZeroPing: true})
+	c.RemoveGame("q3a")
+
+	restored, err := LoadBlockList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := restored.Rules("csgo"); !reflect.DeepEqual(rules, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, rules, result))
+	}
+	if result := restored.Rules("q3a"); !reflect.DeepEqual(BlockRules{}, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, BlockRules{}, result))
+	}
+}
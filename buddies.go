@@ -0,0 +1,238 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/skybon/semaphore"
+)
+
+// Buddy is a player to watch for. Name is compared without color codes and case, it may contain shell wildcards. Empty Games means every game.
+type Buddy struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Games []GameID `json:"games"`
+}
+
+func (b Buddy) query() PlayerQuery {
+	match := PlayerMatchExact
+	if strings.ContainsAny(b.Name, "*?[") {
+		match = PlayerMatchPattern
+	}
+
+	return PlayerQuery{Name: b.Name, Match: match, IgnoreColors: true, GameIDs: b.Games}
+}
+
+func (b Buddy) validate() error {
+	if b.ID == "" {
+		return errInvalidID
+	}
+
+	query := b.query()
+	return ValidatePlayerQuery(&query)
+}
+
+// BuddyPresence tells where a buddy currently plays.
+type BuddyPresence struct {
+	BuddyID    string    `json:"buddy_id"`
+	Online     bool      `json:"online"`
+	GameID     GameID    `json:"game_id,omitempty"`
+	Host       string    `json:"host,omitempty"`
+	ServerName string    `json:"server_name,omitempty"`
+	Map        string    `json:"map,omitempty"`
+	PlayerName string    `json:"player_name,omitempty"`
+	Since      time.Time `json:"since"`
+}
+
+func (p BuddyPresence) sameServer(o BuddyPresence) bool {
+	return p.GameID == o.GameID && p.Host == o.Host
+}
+
+const (
+	BuddyAppeared = "appeared"
+	BuddyLeft     = "left"
+	BuddySwitched = "switched"
+)
+
+type BuddyEvent struct {
+	Type     string        `json:"type"`
+	Time     time.Time     `json:"time"`
+	Buddy    Buddy         `json:"buddy"`
+	Presence BuddyPresence `json:"presence"`
+	Previous BuddyPresence `json:"previous"`
+}
+
+type buddyListDump struct {
+	Buddies  []Buddy  `json:"buddies"`
+	Webhooks []string `json:"webhooks"`
+}
+
+// BuddyList keeps watched players, their presence and the webhooks that are notified about presence changes. OnEvents receives every non-empty batch of presence changes.
+type BuddyList struct {
+	OnEvents func([]BuddyEvent)
+
+	path      string
+	buddies   map[string]Buddy
+	webhooks  []string
+	presence  map[string]BuddyPresence
+	semaphore semaphore.Semaphore
+}
+
+func (c *BuddyList) save() error {
+	dump := buddyListDump{Buddies: c.all(), Webhooks: c.webhooks}
+
+	return saveJSONFile(c.path, dump)
+}
+
+func (c *BuddyList) all() []Buddy {
+	output := make([]Buddy, 0, len(c.buddies))
+	for _, v := range c.buddies {
+		output = append(output, v)
+	}
+	sort.Slice(output, func(i, j int) bool { return output[i].ID < output[j].ID })
+
+	return output
+}
+
+func (c *BuddyList) All() (output []Buddy) {
+	c.semaphore.Exec(func() { output = c.all() })
+
+	return output
+}
+
+// Upsert adds the buddy or replaces the one with the same ID.
+func (c *BuddyList) Upsert(b Buddy) (err error) {
+	if err = b.validate(); err != nil {
+		return err
+	}
+
+	c.semaphore.Exec(func() {
+		c.buddies[b.ID] = b
+		err = c.save()
+	})
+
+	return err
+}
+
+func (c *BuddyList) Remove(id string) (err error) {
+	c.semaphore.Exec(func() {
+		if _, exists := c.buddies[id]; !exists {
+			err = errNoSuchBuddy
+			return
+		}
+		delete(c.buddies, id)
+		delete(c.presence, id)
+		err = c.save()
+	})
+
+	return err
+}
+
+func (c *BuddyList) Webhooks() (output []string) {
+	c.semaphore.Exec(func() { output = append([]string{}, c.webhooks...) })
+
+	return output
+}
+
+func (c *BuddyList) SetWebhooks(urls []string) (err error) {
+	c.semaphore.Exec(func() {
+		c.webhooks = append([]string{}, urls...)
+		err = c.save()
+	})
+
+	return err
+}
+
+// Presence returns the last known presence of every buddy.
+func (c *BuddyList) Presence() (output []BuddyPresence) {
+	c.semaphore.Exec(func() {
+		output = make([]BuddyPresence, 0, len(c.buddies))
+		for _, b := range c.all() {
+			p, exists := c.presence[b.ID]
+			if !exists {
+				p = BuddyPresence{BuddyID: b.ID}
+			}
+			output = append(output, p)
+		}
+	})
+
+	return output
+}
+
+func currentPresence(b Buddy, previous BuddyPresence, matches []PlayerMatch, t time.Time) BuddyPresence {
+	if len(matches) == 0 {
+		if !previous.Online {
+			return previous
+		}
+		return BuddyPresence{BuddyID: b.ID, Since: t}
+	}
+
+	match := matches[0]
+	for _, m := range matches {
+		if m.GameID == previous.GameID && m.Host == previous.Host {
+			match = m
+			break
+		}
+	}
+
+	output := BuddyPresence{BuddyID: b.ID, Online: true, GameID: match.GameID, Host: match.Host, ServerName: match.ServerName, Map: match.Map, PlayerName: match.Name, Since: t}
+	if previous.Online && output.sameServer(previous) {
+		output.Since = previous.Since
+	}
+
+	return output
+}
+
+// Update recomputes presence of every buddy from the player index and reports the changes.
+func (c *BuddyList) Update(players *PlayerIndex, t time.Time) (events []BuddyEvent) {
+	c.semaphore.Exec(func() {
+		for _, b := range c.all() {
+			previous := c.presence[b.ID]
+			previous.BuddyID = b.ID
+			current := currentPresence(b, previous, players.Search(b.query()), t)
+			c.presence[b.ID] = current
+
+			event := BuddyEvent{Time: t, Buddy: b, Presence: current, Previous: previous}
+			switch {
+			case current.Online && !previous.Online:
+				event.Type = BuddyAppeared
+			case !current.Online && previous.Online:
+				event.Type = BuddyLeft
+			case current.Online && !current.sameServer(previous):
+				event.Type = BuddySwitched
+			default:
+				continue
+			}
+			events = append(events, event)
+		}
+	})
+
+	if len(events) > 0 && c.OnEvents != nil {
+		c.OnEvents(events)
+	}
+
+	return events
+}
+
+// LoadBuddyList reads the buddy list from path. Empty path keeps the list in memory only.
+func LoadBuddyList(path string) (*BuddyList, error) {
+	c := &BuddyList{path: path, buddies: map[string]Buddy{}, presence: map[string]BuddyPresence{}, semaphore: semaphore.MakeSemaphore(1)}
+
+	var dump buddyListDump
+	if err := loadJSONFile(path, &dump); err != nil {
+		return nil, err
+	}
+	for _, b := range dump.Buddies {
+		c.buddies[b.ID] = b
+	}
+	c.webhooks = dump.Webhooks
+
+	return c, nil
+}
+
+// MakeBuddyList creates an empty in-memory buddy list.
+func MakeBuddyList() *BuddyList {
+	c, _ := LoadBuddyList("")
+	return c
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skybon/goutil"
+)
+
+func buddyEventTypes(events []BuddyEvent) []string {
+	output := []string{}
+	for _, e := range events {
+		output = append(output, e.Buddy.ID+":"+e.Type)
+	}
+	return output
+}
+
+func TestBuddyListUpdate(t *testing.T) {
+	c := MakeBuddyList()
+	if err := c.Upsert(Buddy{ID: "redeye", Name: "RedEye"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Upsert(Buddy{ID: "grunts", Name: "grunt*", Games: []GameID{"q3a"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Upsert(Buddy{ID: ""}); err != errInvalidID {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errInvalidID.Error(), err))
+	}
+
+	var notified []BuddyEvent
+	c.OnEvents = func(events []BuddyEvent) { notified = append(notified, events...) }
+
+	players := MakePlayerIndex()
+	t0 := time.Unix(1000, 0)
+	players.Rebuild("q3a", []ServerData{{Host: "a:1", Name: "A", Players: []PlayerData{makeTestPlayer("^1Red^7Eye", "0", "10")}}})
+	players.Rebuild("xonotic", []ServerData{{Host: "c:1", Name: "C", Players: []PlayerData{makeTestPlayer("Grunt2", "0", "10")}}})
+
+	fixtures := []struct {
+		Servers map[GameID][]ServerData
+		Result  []string
+	}{
+		{nil, []string{"redeye:appeared"}},
+		{map[GameID][]ServerData{"q3a": {{Host: "a:1", Players: []PlayerData{makeTestPlayer("redeye", "0", "10"), makeTestPlayer("grunt", "0", "10")}}}}, []string{"grunts:appeared"}},
+		{map[GameID][]ServerData{"q3a": {{Host: "b:1", Players: []PlayerData{makeTestPlayer("redeye", "0", "10"), makeTestPlayer("grunt", "0", "10")}}}}, []string{"grunts:switched", "redeye:switched"}},
+		{map[GameID][]ServerData{"q3a": nil}, []string{"grunts:left", "redeye:left"}},
+		{map[GameID][]ServerData{"q3a": nil}, []string{}},
+	}
+
+	for i, fixture := range fixtures {
+		for id, servers := range fixture.Servers {
+			players.Rebuild(id, servers)
+		}
+		result := buddyEventTypes(c.Update(players, t0.Add(time.Duration(i)*time.Minute)))
+		if !reflect.DeepEqual(fixture.Result, result) {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture.Result, result))
+		}
+	}
+
+	if len(notified) != 6 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, 6, len(notified)))
+	}
+	if p := c.Presence(); len(p) != 2 || p[0].Online || p[1].Online {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, "all offline", p))
+	}
+}
+
+func TestBuddyListPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buddies.json")
+
+	c, err := LoadBuddyList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Upsert(Buddy{ID: "redeye", Name: "RedEye", Games: []GameID{"q3a"}})
+	c.Upsert(Buddy{ID: "grunt", Name: "Grunt"})
+	c.SetWebhooks([]string{"http://localhost/hook"})
+	if err := c.Remove("grunt"); err != nil {
+		t.Error(err)
+	}
+	if err := c.Remove("grunt"); err != errNoSuchBuddy {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errNoSuchBuddy.Error(), err))
+	}
+
+	restored, err := LoadBuddyList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.All(), restored.All()) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, c.All(), restored.All()))
+	}
+	if !reflect.DeepEqual(c.Webhooks(), restored.Webhooks()) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, c.Webhooks(), restored.Webhooks()))
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Catalog asset file names.
+const (
+	CatalogGameListsFile = "game_lists.toml"
+	CatalogDefaultsFile  = "default_game_settings.toml"
+)
+
+// CatalogGame describes a game known to Obozrenie: how to query it, which settings it uses and their defaults.
+type CatalogGame struct {
+	Name          string                 `json:"name"`
+	Proxy         ProxyID                `json:"proxy"`
+	Adapter       AdapterID              `json:"adapter"`
+	LaunchPattern string                 `json:"launch_pattern"`
+	SteamAppID    string                 `json:"steam_app_id"`
+	Settings      []string               `json:"settings"`
+	Tags          []string               `json:"tags"`
+	ProxyOptions  map[string]interface{} `json:"proxy_options"`
+	Defaults      map[string]interface{} `json:"defaults"`
+}
+
+// AllowsSetting tells whether the key is listed in the game's settings.
+func (g CatalogGame) AllowsSetting(key string) bool {
+	for _, v := range g.Settings {
+		if v == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Catalog is the list of known games loaded from game_lists.toml and default_game_settings.toml.
+type Catalog struct {
+	Games map[GameID]CatalogGame
+	Order []GameID
+}
+
+// Game returns the catalog description of the game.
+func (c *Catalog) Game(id GameID) (g CatalogGame, exists bool) {
+	g, exists = c.Games[id]
+
+	return g, exists
+}
+
+// catalogGame returns the catalog description of a game in the game table, following its CatalogID.
+func (c *Core) catalogGame(id GameID) (CatalogGame, bool) {
+	return c.catalog().Game(c.catalogID(id))
+}
+
+func (c *Core) catalogID(id GameID) GameID {
+	info, _ := c.GameTable.GameInfo(id)
+
+	return info.CatalogBase(id)
+}
+
+// CheckSetting fails with errUnknownSetting if the game is in the catalog and does not list the key among its settings. Games missing from the catalog accept any key.
+func (c *Catalog) CheckSetting(id GameID, key string) error {
+	g, exists := c.Game(id)
+	if exists && !g.AllowsSetting(key) {
+		return fmt.Errorf("%s: %v", key, errUnknownSetting)
+	}
+
+	return nil
+}
+
+func tomlString(table map[string]interface{}, key string) string {
+	v, _ := table[key].(string)
+
+	return v
+}
+
+func tomlStrings(table map[string]interface{}, key string) []string {
+	list, _ := table[key].([]interface{})
+	output := make([]string, 0, len(list))
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			output = append(output, s)
+		}
+	}
+
+	return output
+}
+
+// ParseCatalog builds the catalog from the contents of game_lists.toml and default_game_settings.toml.
+func ParseCatalog(gameLists []byte, defaults []byte) (*Catalog, error) {
+	games, order, err := decodeTOML(gameLists)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", CatalogGameListsFile, err)
+	}
+	defaultTables, _, err := decodeTOML(defaults)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", CatalogDefaultsFile, err)
+	}
+
+	c := &Catalog{Games: map[GameID]CatalogGame{}}
+	for _, name := range order {
+		if name == "" || strings.Contains(name, ".") {
+			continue
+		}
+		table := games[name]
+
+		g := CatalogGame{
+			Name:          tomlString(table, "name"),
+			Proxy:         ProxyID(tomlString(table, "proxy")),
+			Adapter:       AdapterID(tomlString(table, "adapter")),
+			LaunchPattern: tomlString(table, "launch_pattern"),
+			SteamAppID:    tomlString(table, "steam_app_id"),
+			Settings:      tomlStrings(table, "settings"),
+			Tags:          tomlStrings(table, "tags"),
+			ProxyOptions:  games[name+".proxy"],
+			Defaults:      defaultTables[name],
+		}
+		if g.ProxyOptions == nil {
+			g.ProxyOptions = map[string]interface{}{}
+		}
+		if g.Defaults == nil {
+			g.Defaults = map[string]interface{}{}
+		}
+
+		c.Games[GameID(name)] = g
+		c.Order = append(c.Order, GameID(name))
+	}
+
+	return c, nil
+}
+
+// LoadCatalog reads the catalog files from the directory.
+func LoadCatalog(dir string) (*Catalog, error) {
+	gameLists, err := os.ReadFile(filepath.Join(dir, CatalogGameListsFile))
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := os.ReadFile(filepath.Join(dir, CatalogDefaultsFile))
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCatalog(gameLists, defaults)
+}
+
+// MakeCatalog creates an empty catalog.
+func MakeCatalog() *Catalog {
+	return &Catalog{Games: map[GameID]CatalogGame{}}
+}
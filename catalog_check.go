@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// CatalogProblem is an inconsistency found in a catalog game.
+type CatalogProblem struct {
+	GameID  GameID `json:"game_id"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (p CatalogProblem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.GameID, p.Field, p.Message)
+}
+
+// checkCatalogGame lists the problems of a single game.
+func (c *Core) checkCatalogGame(id GameID, g CatalogGame) []CatalogProblem {
+	var output []CatalogProblem
+	add := func(field string, format string, args ...interface{}) {
+		output = append(output, CatalogProblem{GameID: id, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if g.Name == "" {
+		add("name", "name is empty")
+	}
+	if _, exists := c.Proxies.Retrieve(g.Proxy); !exists {
+		add("proxy", "proxy %q is not registered", g.Proxy)
+	}
+	if _, exists := c.Adapters.Retrieve(g.Adapter); !exists {
+		add("adapter", "adapter %q is not registered", g.Adapter)
+	}
+	if !LaunchPatternExists(g.LaunchPattern) {
+		add("launch_pattern", "launch pattern %q is not known", g.LaunchPattern)
+	}
+
+	for _, k := range g.Settings {
+		if _, exists := g.Defaults[k]; !exists {
+			add("settings", "%q has no default", k)
+		}
+	}
+
+	keys := make([]string, 0, len(g.Defaults))
+	for k := range g.Defaults {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !g.AllowsSetting(k) {
+			add("defaults", "%q is not listed in settings", k)
+			continue
+		}
+		t := LookupSettingSpec(k).Type
+		if emptyPath(t, g.Defaults[k]) {
+			continue
+		}
+		if _, err := EncodeSettingValue(t, g.Defaults[k]); err != nil {
+			add("defaults", "%s: %v", k, err)
+		}
+	}
+
+	return output
+}
+
+// CheckCatalog verifies the catalog against the registered proxies and adapters, the known launch patterns and the setting schema.
+func (c *Core) CheckCatalog(catalog *Catalog) []CatalogProblem {
+	var output []CatalogProblem
+	for _, id := range catalog.Order {
+		output = append(output, c.checkCatalogGame(id, catalog.Games[id])...)
+	}
+
+	return output
+}
+
+// checkCatalogDir loads the catalog from the directory and writes a report of its problems. It returns false if the catalog cannot be loaded or has problems.
+func checkCatalogDir(c *Core, dir string, w io.Writer) bool {
+	catalog, err := LoadCatalog(dir)
+	if err != nil {
+		fmt.Fprintf(w, "Catalog cannot be loaded: %s\n", err.Error())
+		return false
+	}
+
+	problems := c.CheckCatalog(catalog)
+	games := map[GameID]bool{}
+	for _, p := range problems {
+		fmt.Fprintln(w, p)
+		games[p.GameID] = true
+	}
+	fmt.Fprintf(w, "Checked %d games: %d problems in %d games.\n", len(catalog.Order), len(problems), len(games))
+
+	return len(problems) == 0
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestCheckCatalog(t *testing.T) {
+	c := StartCore(MakeMemGameTable())
+	c.Proxies.Insert(ProxyNetHTTP, func(GameInfo, SettingsMap) ([]string, error) { return nil, nil })
+
+	gameLists := testGameLists + `
+[broken]
+name = ""
+proxy = "gopher"
+adapter = "qstat_xml"
+launch_pattern = "doom"
+settings = ["path", "master_uri"]
+`
+	defaults := testGameSettings + `
+[broken]
+master_uri = ["servers.example.com", "master://example.com:27950"]
+workdir = ""
+`
+	catalog, err := ParseCatalog([]byte(gameLists), []byte(defaults))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := []CatalogProblem{
+		{"minetest", "adapter", `adapter "minetest" is not registered`},
+		{"broken", "name", "name is empty"},
+		{"broken", "proxy", `proxy "gopher" is not registered`},
+		{"broken", "launch_pattern", `launch pattern "doom" is not known`},
+		{"broken", "settings", `"path" has no default`},
+		{"broken", "defaults", `master_uri: "servers.example.com": ` + errSettingNotURIList.Error()},
+		{"broken", "defaults", `"workdir" is not listed in settings`},
+	}
+	result := c.CheckCatalog(catalog)
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+}
+
+func TestCheckCatalogDir(t *testing.T) {
+	var b bytes.Buffer
+	if checkCatalogDir(StartCore(MakeMemGameTable()), "assets", &b) {
+		t.Error("shipped catalog passes the check without net_http, minetest and rigsofrods")
+	}
+	if !strings.Contains(b.String(), `minetest: adapter: adapter "minetest" is not registered`) {
+		t.Error(b.String())
+	}
+
+	b.Reset()
+	if checkCatalogDir(StartCore(MakeMemGameTable()), t.TempDir(), &b) || !strings.HasPrefix(b.String(), "Catalog cannot be loaded") {
+		t.Error(b.String())
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Catalog change actions.
+const (
+	CatalogGameAdded   = "added"
+	CatalogGameRemoved = "removed"
+	CatalogGameChanged = "changed"
+)
+
+// CatalogChange describes how a game differs between two catalogs and which game table values a reload updated. Updates and errors of clones and renamed games based on the catalog game are prefixed with their ID.
+type CatalogChange struct {
+	GameID   GameID                 `json:"game_id"`
+	Action   string                 `json:"action"`
+	Info     map[string]FieldChange `json:"info,omitempty"`
+	Defaults map[string]FieldChange `json:"defaults,omitempty"`
+	Updated  []string               `json:"updated,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+func catalogInfoFields(g CatalogGame) map[string]interface{} {
+	return map[string]interface{}{"name": g.Name, "proxy": string(g.Proxy), "adapter": string(g.Adapter), "steam_app_id": g.SteamAppID, "launch_pattern": g.LaunchPattern, "settings": g.Settings, "tags": g.Tags, "proxy_options": g.ProxyOptions}
+}
+
+// diffMaps lists the keys that differ between the maps, including the ones present in only one of them.
+func diffMaps(old map[string]interface{}, new map[string]interface{}) map[string]FieldChange {
+	output := diffFields(old, new)
+	for k, v := range old {
+		if _, exists := new[k]; !exists {
+			output[k] = FieldChange{Old: v}
+		}
+	}
+
+	return output
+}
+
+// DiffCatalogs compares two catalogs game by game. Games are listed in the order of the new catalog, followed by the removed ones.
+func DiffCatalogs(old *Catalog, new *Catalog) []CatalogChange {
+	var output []CatalogChange
+	for _, id := range new.Order {
+		g := new.Games[id]
+		prev, exists := old.Game(id)
+		if !exists {
+			output = append(output, CatalogChange{GameID: id, Action: CatalogGameAdded})
+			continue
+		}
+
+		change := CatalogChange{GameID: id, Action: CatalogGameChanged, Info: diffMaps(catalogInfoFields(prev), catalogInfoFields(g)), Defaults: diffMaps(prev.Defaults, g.Defaults)}
+		if len(change.Info) > 0 || len(change.Defaults) > 0 {
+			output = append(output, change)
+		}
+	}
+
+	var removed []GameID
+	for id := range old.Games {
+		if _, exists := new.Game(id); !exists {
+			removed = append(removed, id)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i] < removed[j] })
+	for _, id := range removed {
+		output = append(output, CatalogChange{GameID: id, Action: CatalogGameRemoved})
+	}
+
+	return output
+}
+
+// applyCatalogChange updates the info fields and stored settings of the game that still hold the old catalog values and lists the updated fields. Values that differ from the old catalog were set by the user and are kept.
+func (c *Core) applyCatalogChange(id GameID, change CatalogChange, old CatalogGame, new CatalogGame) (updated []string, err error) {
+	info, err := c.GameTable.GameInfo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	infoChanged := false
+	if _, changed := change.Info["name"]; changed && info.Name == old.Name {
+		info.Name, infoChanged = new.Name, true
+		updated = append(updated, "name")
+	}
+	if _, changed := change.Info["proxy"]; changed && info.Proxy == old.Proxy {
+		info.Proxy, infoChanged = new.Proxy, true
+		updated = append(updated, "proxy")
+	}
+	if _, changed := change.Info["adapter"]; changed && info.Adapter == old.Adapter {
+		info.Adapter, infoChanged = new.Adapter, true
+		updated = append(updated, "adapter")
+	}
+	if _, changed := change.Info["steam_app_id"]; changed && info.SteamAppID == old.SteamAppID {
+		info.SteamAppID, infoChanged = new.SteamAppID, true
+		updated = append(updated, "steam_app_id")
+	}
+	if infoChanged {
+		if err := c.CheckGameInfo(info); err != nil {
+			return nil, err
+		}
+		if err := c.GameTable.SetGameInfo(id, info); err != nil {
+			return nil, err
+		}
+	}
+
+	settings, err := c.GameTable.Settings(id)
+	if err != nil {
+		return updated, err
+	}
+	keys := make([]string, 0, len(change.Defaults))
+	for k := range change.Defaults {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		stored, exists := settings[k]
+		if !exists {
+			continue
+		}
+		spec := LookupSettingSpec(k)
+		var prev string
+		if !emptyPath(spec.Type, old.Defaults[k]) {
+			if prev, err = EncodeSettingValue(spec.Type, old.Defaults[k]); err != nil {
+				continue
+			}
+		}
+		if stored != prev {
+			continue
+		}
+
+		if v, present := new.Defaults[k]; present && !emptyPath(spec.Type, v) {
+			s, encodeErr := EncodeSettingValue(spec.Type, v)
+			if encodeErr != nil {
+				continue
+			}
+			err = c.GameTable.SetSetting(id, k, s)
+		} else {
+			err = c.GameTable.RemoveSetting(id, k)
+		}
+		if err != nil {
+			return updated, err
+		}
+		updated = append(updated, "settings."+k)
+	}
+
+	return updated, nil
+}
+
+// ReloadCatalog replaces the catalog and carries its changes over to the games in the game table, including clones and renamed games based on a changed catalog game. Defaults are resolved from the catalog, so only stored values equal to the old catalog's are rewritten. Server lists are left alone. Games that could not be updated have the error set in their change.
+func (c *Core) ReloadCatalog(catalog *Catalog) []CatalogChange {
+	old := c.swapCatalog(catalog)
+	changes := DiffCatalogs(old, catalog)
+
+	based := map[GameID][]GameID{}
+	for _, id := range c.GameTable.AllGames() {
+		if info, err := c.GameTable.GameInfo(id); err == nil {
+			base := info.CatalogBase(id)
+			based[base] = append(based[base], id)
+		}
+	}
+
+	for i := range changes {
+		change := &changes[i]
+		if change.Action != CatalogGameChanged {
+			continue
+		}
+		ids := based[change.GameID]
+		sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+
+		var errs []string
+		for _, id := range ids {
+			updated, err := c.applyCatalogChange(id, *change, old.Games[change.GameID], catalog.Games[change.GameID])
+			label := ""
+			if id != change.GameID {
+				label = string(id) + ":"
+			}
+			for _, field := range updated {
+				change.Updated = append(change.Updated, label+field)
+			}
+			if err != nil && label != "" {
+				err = fmt.Errorf("%s %v", label, err)
+			}
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		change.Error = strings.Join(errs, "; ")
+	}
+
+	return changes
+}
+
+type catalogFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statCatalogFiles(dir string) map[string]catalogFileState {
+	output := map[string]catalogFileState{}
+	for _, name := range []string{CatalogGameListsFile, CatalogDefaultsFile} {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			output[name] = catalogFileState{modTime: fi.ModTime(), size: fi.Size()}
+		}
+	}
+
+	return output
+}
+
+// catalogWatcher polls the catalog files and calls reload when they change, until stopped.
+type catalogWatcher struct {
+	dir      string
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func (w *catalogWatcher) run(reload func()) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	state := statCatalogFiles(w.dir)
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			current := statCatalogFiles(w.dir)
+			if !reflect.DeepEqual(state, current) {
+				state = current
+				reload()
+			}
+		}
+	}
+}
+
+// Stop ends watching and waits for a running reload to finish.
+func (w *catalogWatcher) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+func startCatalogWatcher(dir string, interval time.Duration, reload func()) *catalogWatcher {
+	w := &catalogWatcher{dir: dir, interval: interval, stop: make(chan struct{}), done: make(chan struct{})}
+	go w.run(reload)
+
+	return w
+}
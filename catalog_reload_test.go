@@ -0,0 +1,175 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skybon/goutil"
+)
+
+func TestDiffCatalogs(t *testing.T) {
+	old, _ := ParseCatalog([]byte(testGameLists), []byte(testGameSettings))
+	new, _ := ParseCatalog([]byte(testGameLists+"\n[q3a]\nname = \"Quake III Arena\"\n"), []byte(strings.Replace(testGameSettings, "steam_launch = true\n", "", 1)))
+	delete(new.Games, "minetest")
+	new.Order = []GameID{"tf", "q3a"}
+	g := new.Games["tf"]
+	g.Name = "TF2"
+	new.Games["tf"] = g
+
+	fixture := []CatalogChange{
+		{GameID: "tf", Action: CatalogGameChanged, Info: map[string]FieldChange{"name": {"Team Fortress 2", "TF2"}}, Defaults: map[string]FieldChange{"steam_launch": {Old: true}}},
+		{GameID: "q3a", Action: CatalogGameAdded},
+		{GameID: "minetest", Action: CatalogGameRemoved},
+	}
+	result := DiffCatalogs(old, new)
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+
+	if result := DiffCatalogs(old, old); len(result) != 0 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, nil, result))
+	}
+}
+
+func TestReloadCatalog(t *testing.T) {
+	c := makeTestCatalogCore(t)
+	c.ImportGameCollection([]byte(testGameLists), []byte(testGameSettings), false)
+	c.GameTable.SetGameInfo("tf", GameInfo{Name: "My TF2", Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML, SteamAppID: "440"})
+	c.GameTable.SetSetting("tf", "path", "/opt/tf/hl2_linux")
+	c.GameTable.InsertServers("minetest", []ServerData{{Host: "127.0.0.1:30000", Name: "Local"}})
+
+	gameLists := strings.Replace(strings.Replace(testGameLists, `"Team Fortress 2"`, `"Team Fortress 2 (updated)"`, 1), `name = "Minetest"`, `name = "Luanti"`, 1)
+	defaults := strings.Replace(strings.Replace(testGameSettings, `"Player"`, `"Guest"`, 1), `path = "hl2_linux"`, `path = "tf_linux"`, 1)
+	catalog, err := ParseCatalog([]byte(gameLists), []byte(defaults))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := c.ReloadCatalog(catalog)
+	updated := map[GameID][]string{}
+	for _, v := range changes {
+		updated[v.GameID] = v.Updated
+	}
+	fixture := map[GameID][]string{"minetest": {"name", "settings.nickname"}, "tf": nil}
+	if !reflect.DeepEqual(fixture, updated) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, updated))
+	}
+
+	if c.catalog() != catalog {
+		t.Error("catalog not replaced")
+	}
+	info, _ := c.GameTable.GameInfo("minetest")
+	expectGameTableValue(t, "minetest name", "Luanti", info.Name)
+	info, _ = c.GameTable.GameInfo("tf")
+	expectGameTableValue(t, "tf name", "My TF2", info.Name)
+	expectGameTableSettings(t, c.GameTable, "minetest", SettingsMap{"path": "minetest", "master_uri": "http://servers.minetest.net", "nickname": "Guest"})
+	expectGameTableSettings(t, c.GameTable, "tf", SettingsMap{"path": "/opt/tf/hl2_linux", "master_uri": "master://hl2master.steampowered.com:27011", "steam_launch": "true"})
+	servers, _ := c.GameTable.AllServers("minetest")
+	expectGameTableValue(t, "minetest servers", 1, len(servers))
+}
+
+func TestReloadCatalogUpdatesDerivedGames(t *testing.T) {
+	c := makeTestCatalogCore(t)
+	c.SteamRoots = nil
+	c.ImportGameCollection([]byte(testGameLists), []byte(testGameSettings), false)
+	if err := c.CloneGame("minetest", "minetest-lan", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RenameGame("tf", "tf2"); err != nil {
+		t.Fatal(err)
+	}
+
+	gameLists := strings.Replace(strings.Replace(testGameLists, `"Team Fortress 2"`, `"Team Fortress 2 (updated)"`, 1), `name = "Minetest"`, `name = "Luanti"`, 1)
+	defaults := strings.Replace(testGameSettings, `"Player"`, `"Guest"`, 1)
+	catalog, err := ParseCatalog([]byte(gameLists), []byte(defaults))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated := map[GameID][]string{}
+	for _, v := range c.ReloadCatalog(catalog) {
+		updated[v.GameID] = v.Updated
+	}
+	fixture := map[GameID][]string{"minetest": {"name", "settings.nickname", "minetest-lan:name", "minetest-lan:settings.nickname"}, "tf": {"tf2:name"}}
+	if !reflect.DeepEqual(fixture, updated) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, updated))
+	}
+
+	info, _ := c.GameTable.GameInfo("minetest-lan")
+	expectGameTableValue(t, "clone name", "Luanti", info.Name)
+	info, _ = c.GameTable.GameInfo("tf2")
+	expectGameTableValue(t, "renamed name", "Team Fortress 2 (updated)", info.Name)
+}
+
+func TestReloadCatalogChecksAdapter(t *testing.T) {
+	c := makeTestCatalogCore(t)
+	c.ImportGameCollection([]byte(testGameLists), []byte(testGameSettings), false)
+
+	catalog, err := ParseCatalog([]byte(strings.Replace(testGameLists, `adapter = "qstat_xml"`, `adapter = "minetest"`, 1)), []byte(testGameSettings))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := c.ReloadCatalog(catalog)
+	if len(changes) != 1 || changes[0].Error == "" {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, "incompatible adapter error", changes))
+	}
+	info, _ := c.GameTable.GameInfo("tf")
+	expectGameTableValue(t, "tf adapter", AdapterQStatXML, info.Adapter)
+}
+
+// TestReloadCatalogConcurrentReads is meant for -race: reloads from the watcher must not race with request handlers reading the catalog.
+func TestReloadCatalogConcurrentReads(t *testing.T) {
+	c := makeTestCatalogCore(t)
+	c.ImportGameCollection([]byte(testGameLists), []byte(testGameSettings), false)
+	catalog, err := ParseCatalog([]byte(strings.Replace(testGameLists, `name = "Minetest"`, `name = "Luanti"`, 1)), []byte(testGameSettings))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			c.ReloadCatalog(catalog)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		c.ResolvedSettings("minetest")
+		c.CheckSettings("minetest", map[string]interface{}{"nickname": "Grunt"})
+		c.GameTags("minetest")
+		c.ExportGameCollection()
+	}
+	<-done
+}
+
+func TestCatalogWatcher(t *testing.T) {
+	dir := t.TempDir()
+	write := func(gameLists string) {
+		os.WriteFile(filepath.Join(dir, CatalogGameListsFile), []byte(gameLists), 0644)
+		os.WriteFile(filepath.Join(dir, CatalogDefaultsFile), []byte(testGameSettings), 0644)
+	}
+	write(testGameLists)
+
+	reloads := make(chan struct{}, 10)
+	w := startCatalogWatcher(dir, 10*time.Millisecond, func() { reloads <- struct{}{} })
+	defer w.Stop()
+
+	time.Sleep(30 * time.Millisecond)
+	select {
+	case <-reloads:
+		t.Fatal("reload without changes")
+	default:
+	}
+
+	write(testGameLists + "\n[q3a]\nname = \"Quake III Arena\"\n")
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("no reload after change")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	c, err := LoadCatalog("assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Order) != len(c.Games) || len(c.Games) == 0 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, len(c.Games), len(c.Order)))
+	}
+	if c.Order[0] != "rigsofrods" {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, "rigsofrods", c.Order[0]))
+	}
+
+	csgo, exists := c.Game("csgo")
+	if !exists {
+		t.Fatal("csgo is missing from the catalog")
+	}
+	fixture := CatalogGame{
+		Name:          "Counter-Strike: Global Offensive",
+		Proxy:         ProxyQStatOutput,
+		Adapter:       AdapterQStatXML,
+		LaunchPattern: "hl2",
+		SteamAppID:    "730",
+		Settings:      []string{"path", "workdir", "master_uri", "query_timeout", "query_retries", "steam_launch", "steam_path"},
+		Tags:          []string{"engine:source", "genre:shooter"},
+		ProxyOptions:  map[string]interface{}{"master_type": "STM", "server_type": "A2S", "server_gametype": "csgo"},
+		Defaults: map[string]interface{}{
+			"path":          "~/.local/share/Steam/steamapps/common/Counter-Strike Global Offensive/csgo_linux",
+			"workdir":       "~/.local/share/Steam/steamapps/common/Counter-Strike Global Offensive",
+			"master_uri":    []interface{}{"master://hl2master.steampowered.com:27011"},
+			"query_timeout": "1m",
+			"query_retries": int64(3),
+			"steam_launch":  true,
+			"steam_path":    "steam",
+		},
+	}
+	if !reflect.DeepEqual(fixture, csgo) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, csgo))
+	}
+
+	if err := c.CheckSetting("csgo", "nickname"); err == nil {
+		t.Error("nickname must not be accepted for csgo")
+	}
+	if err := c.CheckSetting("custom", "anything"); err != nil {
+		t.Error(err)
+	}
+}
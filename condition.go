@@ -0,0 +1,407 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ConditionError reports a malformed condition expression and the position it was found at.
+type ConditionError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("Invalid condition at position %d: %s", e.Pos, e.Msg)
+}
+
+type condKind int
+
+const (
+	condString condKind = iota
+	condNumber
+	condBool
+	// condSetting is a server setting. It is a string which may be compared to numbers.
+	condSetting
+)
+
+type condValue struct {
+	s string
+	n float64
+	b bool
+}
+
+type condField struct {
+	kind condKind
+	get  func(ServerData) condValue
+}
+
+// conditionFields are ServerData fields usable in conditions. Server settings are available as settings.<key>.
+var conditionFields = map[string]condField{
+	"host":       {condString, func(v ServerData) condValue { return condValue{s: v.Host} }},
+	"name":       {condString, func(v ServerData) condValue { return condValue{s: v.Name} }},
+	"status":     {condString, func(v ServerData) condValue { return condValue{s: v.Status} }},
+	"map":        {condString, func(v ServerData) condValue { return condValue{s: v.Map} }},
+	"gametype":   {condString, func(v ServerData) condValue { return condValue{s: v.GameType} }},
+	"ping":       {condNumber, func(v ServerData) condValue { return condValue{n: float64(v.Ping)} }},
+	"secure":     {condBool, func(v ServerData) condValue { return condValue{b: v.Secure} }},
+	"numplayers": {condNumber, func(v ServerData) condValue { return condValue{n: float64(v.NumPlayers)} }},
+	"maxplayers": {condNumber, func(v ServerData) condValue { return condValue{n: float64(v.MaxPlayers)} }},
+}
+
+const settingsFieldPrefix = "settings."
+
+type condNode interface {
+	kind() condKind
+	eval(ServerData) condValue
+}
+
+type condLiteral struct {
+	k condKind
+	v condValue
+}
+
+func (n condLiteral) kind() condKind            { return n.k }
+func (n condLiteral) eval(ServerData) condValue { return n.v }
+
+type condFieldRef struct{ field condField }
+
+func (n condFieldRef) kind() condKind              { return n.field.kind }
+func (n condFieldRef) eval(v ServerData) condValue { return n.field.get(v) }
+
+type condSettingRef struct{ key string }
+
+func (n condSettingRef) kind() condKind { return condSetting }
+func (n condSettingRef) eval(v ServerData) condValue {
+	s := v.Settings[n.key]
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		f = 0
+	}
+	return condValue{s: s, n: f}
+}
+
+type condNot struct{ x condNode }
+
+func (n condNot) kind() condKind              { return condBool }
+func (n condNot) eval(v ServerData) condValue { return condValue{b: !n.x.eval(v).b} }
+
+type condLogical struct {
+	and  bool
+	x, y condNode
+}
+
+func (n condLogical) kind() condKind { return condBool }
+func (n condLogical) eval(v ServerData) condValue {
+	if n.and {
+		return condValue{b: n.x.eval(v).b && n.y.eval(v).b}
+	}
+	return condValue{b: n.x.eval(v).b || n.y.eval(v).b}
+}
+
+type condCompare struct {
+	op      string
+	numeric bool
+	x, y    condNode
+}
+
+func (n condCompare) kind() condKind { return condBool }
+func (n condCompare) eval(v ServerData) condValue {
+	a, b := n.x.eval(v), n.y.eval(v)
+
+	var c int
+	switch {
+	case n.op == "~":
+		matched, _ := MatchWildcard(strings.ToLower(b.s), strings.ToLower(a.s))
+		return condValue{b: matched}
+	case n.numeric:
+		if a.n < b.n {
+			c = -1
+		} else if a.n > b.n {
+			c = 1
+		}
+	case n.x.kind() == condBool:
+		if a.b != b.b {
+			c = 1
+		}
+	default:
+		c = strings.Compare(a.s, b.s)
+	}
+
+	var result bool
+	switch n.op {
+	case "==":
+		result = c == 0
+	case "!=":
+		result = c != 0
+	case "<":
+		result = c < 0
+	case "<=":
+		result = c <= 0
+	case ">":
+		result = c > 0
+	case ">=":
+		result = c >= 0
+	}
+	return condValue{b: result}
+}
+
+var condCompareOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "~": true}
+
+type condToken struct {
+	pos  int
+	kind string // "ident", "number", "string", "op" or "" at the end of input
+	text string
+}
+
+func tokenizeCondition(s string) ([]condToken, error) {
+	var output []condToken
+
+	for i := 0; i < len(s); {
+		r := rune(s[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(s) && (unicode.IsLetter(rune(s[i])) || unicode.IsDigit(rune(s[i])) || s[i] == '_' || s[i] == '.') {
+				i++
+			}
+			output = append(output, condToken{start, "ident", s[start:i]})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))):
+			start := i
+			i++
+			for i < len(s) && (unicode.IsDigit(rune(s[i])) || s[i] == '.') {
+				i++
+			}
+			output = append(output, condToken{start, "number", s[start:i]})
+		case r == '"':
+			start := i
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(s) {
+				return nil, &ConditionError{start, "unterminated string"}
+			}
+			i++
+			text, err := strconv.Unquote(s[start:i])
+			if err != nil {
+				return nil, &ConditionError{start, "malformed string"}
+			}
+			output = append(output, condToken{start, "string", text})
+		default:
+			var op string
+			for _, v := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "~", "(", ")"} {
+				if strings.HasPrefix(s[i:], v) {
+					op = v
+					break
+				}
+			}
+			if op == "" {
+				return nil, &ConditionError{i, fmt.Sprintf("unexpected character %q", r)}
+			}
+			output = append(output, condToken{i, "op", op})
+			i += len(op)
+		}
+	}
+
+	return append(output, condToken{pos: len(s)}), nil
+}
+
+type condParser struct {
+	tokens []condToken
+	pos    int
+}
+
+func (p *condParser) peek() condToken { return p.tokens[p.pos] }
+
+func (p *condParser) next() condToken {
+	t := p.tokens[p.pos]
+	if t.kind != "" {
+		p.pos++
+	}
+	return t
+}
+
+func (p *condParser) acceptOp(op string) bool {
+	if t := p.peek(); t.kind == "op" && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *condParser) expectBool(n condNode, pos int) error {
+	if n.kind() != condBool {
+		return &ConditionError{pos, "boolean value expected"}
+	}
+	return nil
+}
+
+func (p *condParser) parseOr() (condNode, error) {
+	return p.parseLogical("||", false, p.parseAnd)
+}
+
+func (p *condParser) parseAnd() (condNode, error) {
+	return p.parseLogical("&&", true, p.parseUnary)
+}
+
+func (p *condParser) parseLogical(op string, and bool, operand func() (condNode, error)) (condNode, error) {
+	pos := p.peek().pos
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "op" && p.peek().text == op {
+		if err := p.expectBool(x, pos); err != nil {
+			return nil, err
+		}
+		p.next()
+		pos = p.peek().pos
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectBool(y, pos); err != nil {
+			return nil, err
+		}
+		x = condLogical{and, x, y}
+	}
+	return x, nil
+}
+
+func (p *condParser) parseUnary() (condNode, error) {
+	if p.acceptOp("!") {
+		pos := p.peek().pos
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectBool(x, pos); err != nil {
+			return nil, err
+		}
+		return condNot{x}, nil
+	}
+	if p.acceptOp("(") {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptOp(")") {
+			return nil, &ConditionError{p.peek().pos, "\")\" expected"}
+		}
+		return x, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *condParser) parseComparison() (condNode, error) {
+	pos := p.peek().pos
+	x, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if t.kind != "op" || !condCompareOps[t.text] {
+		return x, nil
+	}
+	p.next()
+
+	y, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	kx, ky := x.kind(), y.kind()
+	n := condCompare{op: t.text, x: x, y: y}
+	switch {
+	case t.text == "~":
+		if (kx != condString && kx != condSetting) || (ky != condString && ky != condSetting) {
+			return nil, &ConditionError{t.pos, "pattern match requires strings"}
+		}
+	case kx == condNumber || ky == condNumber:
+		if (kx != condNumber && kx != condSetting) || (ky != condNumber && ky != condSetting) {
+			return nil, &ConditionError{pos, "cannot compare number with non-number"}
+		}
+		n.numeric = true
+	case kx == condBool || ky == condBool:
+		if kx != ky {
+			return nil, &ConditionError{pos, "cannot compare boolean with non-boolean"}
+		}
+		if t.text != "==" && t.text != "!=" {
+			return nil, &ConditionError{t.pos, "booleans can only be compared for equality"}
+		}
+	}
+
+	return n, nil
+}
+
+func (p *condParser) parseOperand() (condNode, error) {
+	t := p.next()
+	switch t.kind {
+	case "number":
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, &ConditionError{t.pos, "malformed number"}
+		}
+		return condLiteral{condNumber, condValue{n: f}}, nil
+	case "string":
+		return condLiteral{condString, condValue{s: t.text}}, nil
+	case "ident":
+		switch name := strings.ToLower(t.text); {
+		case name == "true" || name == "false":
+			return condLiteral{condBool, condValue{b: name == "true"}}, nil
+		case strings.HasPrefix(name, settingsFieldPrefix) && len(name) > len(settingsFieldPrefix):
+			return condSettingRef{t.text[len(settingsFieldPrefix):]}, nil
+		default:
+			if field, exists := conditionFields[name]; exists {
+				return condFieldRef{field}, nil
+			}
+			return nil, &ConditionError{t.pos, fmt.Sprintf("unknown field %q", t.text)}
+		}
+	case "":
+		return nil, &ConditionError{t.pos, "unexpected end of condition"}
+	}
+
+	return nil, &ConditionError{t.pos, fmt.Sprintf("unexpected %q", t.text)}
+}
+
+// Condition is a compiled boolean expression over a server's fields and settings.
+type Condition struct {
+	root condNode
+}
+
+// Match tells whether the server satisfies the condition.
+func (c *Condition) Match(v ServerData) bool {
+	return c.root.eval(v).b
+}
+
+// CompileCondition parses a condition such as `host == "1.2.3.4:27960" && numplayers >= 6`.
+// Fields are named case-insensitively after ServerData ones, server settings are referred to as settings.<key>.
+// Supported operators are ==, !=, <, <=, >, >=, ~ (case-insensitive wildcard match), !, && and ||.
+func CompileCondition(s string) (*Condition, error) {
+	tokens, err := tokenizeCondition(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &condParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != "" {
+		return nil, &ConditionError{t.pos, fmt.Sprintf("unexpected %q", t.text)}
+	}
+	if err := p.expectBool(root, 0); err != nil {
+		return nil, err
+	}
+
+	return &Condition{root}, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestCompileCondition(t *testing.T) {
+	server := ServerData{Host: "1.2.3.4:27960", Name: "^1Red Arena", Status: "UP", Map: "q3dm17", GameType: "ffa", Ping: 40, NumPlayers: 6, MaxPlayers: 16, Settings: ServerSettings{"g_gametype": "0", "sv_hostname": "Arena"}}
+
+	fixtures := map[string]bool{
+		`host == "1.2.3.4:27960" && NumPlayers >= 6`: true,
+		`host == "1.2.3.4:27960" && numplayers > 6`:  false,
+		`map == "q3dm17"`:                             true,
+		`map != "q3dm17" || ping < 50`:                true,
+		`!(map == "q3dm6") && !secure`:                true,
+		`secure == false && numplayers < maxplayers`:  true,
+		`name ~ "*arena"`:                             true,
+		`settings.g_gametype == 0`:                    true,
+		`settings.sv_hostname == "Arena"`:             true,
+		`settings.missing == ""`:                      true,
+		`(map == "q3dm6" || map == "q3dm17") && true`: true,
+	}
+
+	for input, fixture := range fixtures {
+		c, err := CompileCondition(input)
+		if err != nil {
+			t.Error(input, err)
+			continue
+		}
+		if result := c.Match(server); result != fixture {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result), input)
+		}
+	}
+
+	invalid := []string{
+		``,
+		`map`,
+		`numplayers`,
+		`map == q3dm17`,
+		`numplayers >= "6"`,
+		`secure > true`,
+		`map == "q3dm17" &&`,
+		`(map == "q3dm17"`,
+		`map == "q3dm17`,
+		`map = "q3dm17"`,
+		`numplayers ~ "6"`,
+	}
+
+	for _, input := range invalid {
+		if _, err := CompileCondition(input); err == nil {
+			t.Error("expected error for", input)
+		} else if _, ok := err.(*ConditionError); !ok {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, "*ConditionError", err.Error()))
+		}
+	}
+}
@@ -1,36 +1,91 @@
 package main
 
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
 const (
 	ProxyQStatOutput = ProxyID("qstat_output")
 	ProxyNetHTTP     = ProxyID("net_http")
 	AdapterQStatXML  = AdapterID("qstat_xml")
 )
 
-func GetProxyID(id string) ProxyID {
-	switch id {
-	case string(ProxyQStatOutput):
-		return ProxyQStatOutput
-	case string(ProxyNetHTTP):
-		return ProxyNetHTTP
-	}
+// Core class of Obozrenie.
+type Core struct {
+	GameTable  GameTable
+	Globals    *GlobalSettings
+	Proxies    *ProxyCollection
+	Adapters   *AdapterCollection
+	Installs   *InstallCollection
+	History    *HistoryCollection
+	Stats      *StatsCache
+	Players    *PlayerIndex
+	Buddies    *BuddyList
+	Alerts     *AlertCollection
+	Blocks     *BlockList
+	Trash      *Trash
+	SteamRoots []string
 
-	return ProxyInvalid
+	catalogLock    sync.RWMutex
+	currentCatalog *Catalog
 }
 
-func GetAdapterID(b string) AdapterID {
-	switch b {
-	case string(AdapterQStatXML):
-		return AdapterQStatXML
-	}
+// catalog returns the current catalog. Loaded catalogs are never modified, a reload swaps in a new one.
+func (c *Core) catalog() *Catalog {
+	c.catalogLock.RLock()
+	defer c.catalogLock.RUnlock()
 
-	return AdapterInvalid
+	return c.currentCatalog
 }
 
-// Core class of Obozrenie.
-type Core struct {
-	GameTable GameTable
-	Proxies   *ProxyCollection
-	Adapters  *AdapterCollection
+// SetCatalog replaces the catalog without touching the game table. ReloadCatalog also carries the changes over to the games.
+func (c *Core) SetCatalog(catalog *Catalog) {
+	c.catalogLock.Lock()
+	c.currentCatalog = catalog
+	c.catalogLock.Unlock()
+}
+
+func (c *Core) swapCatalog(catalog *Catalog) (old *Catalog) {
+	c.catalogLock.Lock()
+	old, c.currentCatalog = c.currentCatalog, catalog
+	c.catalogLock.Unlock()
+
+	return old
+}
+
+// CheckGameInfo verifies the game's proxy and adapter against the registries and checks that the adapter can parse the proxy's output. Games with neither pass, they are just not queried, but one without the other is an error.
+func (c *Core) CheckGameInfo(info GameInfo) error {
+	errs := FieldErrors{}
+
+	switch {
+	case info.Proxy != ProxyInvalid && info.Adapter == AdapterInvalid:
+		errs["adapter"] = errUnpairedProxy.Error()
+	case info.Proxy == ProxyInvalid && info.Adapter != AdapterInvalid:
+		errs["proxy"] = errUnpairedProxy.Error()
+	}
+
+	if info.Proxy != ProxyInvalid {
+		if _, exists := c.Proxies.Info(info.Proxy); !exists {
+			errs["proxy"] = fmt.Sprintf("%v: %s", errUnknownProxy, info.Proxy)
+		}
+	}
+	if info.Adapter != AdapterInvalid {
+		adapter, exists := c.Adapters.Info(info.Adapter)
+		switch {
+		case !exists:
+			errs["adapter"] = fmt.Sprintf("%v: %s", errUnknownAdapter, info.Adapter)
+		case info.Proxy != ProxyInvalid && errs["proxy"] == "" && !adapter.AcceptsProxy(info.Proxy):
+			errs["adapter"] = fmt.Sprintf("%v: %s cannot parse %s", errIncompatibleAdapter, info.Adapter, info.Proxy)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
 }
 
 func (c *Core) statMasterTarget(gameID GameID, cb func([]ServerData, error)) {
@@ -61,9 +116,33 @@ func (c *Core) statMasterTarget(gameID GameID, cb func([]ServerData, error)) {
 			}
 		}
 
+		if err == nil {
+			var aExists bool
+			adapterFunc, aExists = c.Adapters.Retrieve(e.Info.Adapter)
+
+			if !aExists {
+				err = errNoAdapter
+			}
+		}
+
+		var settings SettingsMap
+		if err == nil {
+			settings, err = c.ResolvedSettings(gameID)
+		}
+
 		var data []string
 		if err == nil {
-			data, err = proxyFunc(e.Info, e.Settings.AllSettings())
+			if g, exists := c.catalogGame(gameID); exists {
+				for k, v := range g.ProxyOptions {
+					if _, set := settings[k]; !set {
+						settings[k] = fmt.Sprint(v)
+					}
+				}
+			}
+			if len(e.Favorites) > 0 {
+				settings[FavoritesSetting] = favoriteHosts(e.Favorites)
+			}
+			data, err = proxyFunc(e.Info, settings)
 		}
 
 		if err == nil && data == nil {
@@ -71,11 +150,24 @@ func (c *Core) statMasterTarget(gameID GameID, cb func([]ServerData, error)) {
 		}
 
 		if err == nil {
-			result, err = adapterFunc(data, e.Info, e.Settings.AllSettings())
+			result, err = adapterFunc(data, e.Info, settings)
+		}
+
+		if err == nil {
+			result, _ = c.Blocks.Apply(gameID, result, func(v ServerData) bool {
+				_, favorite := e.Favorites[v.Host]
+				return favorite
+			})
+			result = mergeFavorites(result, e.Favorites)
 		}
 
 		if err == nil {
-			c.GameTable.InsertServers(gameID, result)
+			// The refresh replaces the server list: servers the master no longer lists or that got blocked are dropped. Favorites are always in the result, as placeholders if the query did not return them.
+			c.GameTable.ReplaceServers(gameID, result)
+			c.History.Record(gameID, result, time.Now())
+			c.ReindexPlayers(gameID)
+			c.Buddies.Update(c.Players, time.Now())
+			c.Alerts.Evaluate(gameID, result, time.Now())
 			c.GameTable.SetQueryStatus(gameID, QueryReady)
 		} else {
 			c.GameTable.SetQueryStatus(gameID, QueryError)
@@ -87,11 +179,110 @@ func (c *Core) statMasterTarget(gameID GameID, cb func([]ServerData, error)) {
 	}
 }
 
+// ReindexPlayers rebuilds the player search index of the game from its current server list.
+func (c *Core) ReindexPlayers(gameID GameID) error {
+	servers, err := c.GameTable.AllServers(gameID)
+	if err != nil {
+		return err
+	}
+
+	c.Players.Rebuild(gameID, servers)
+
+	return nil
+}
+
 // UpdateServerList refreshes server list for selected game.
 func (c *Core) UpdateServerList(gameID GameID, cb func([]ServerData, error)) {
 	go c.statMasterTarget(gameID, cb)
 }
 
+func (c *Core) detectPathInstallation(gameID GameID) error {
+	settings, err := c.ResolvedSettings(gameID)
+	if err != nil {
+		return err
+	}
+
+	if settings["path"] == "" {
+		c.Installs.Remove(gameID)
+		return nil
+	}
+
+	c.Installs.Insert(gameID, LookupGameExecutable(settings["path"], settings["workdir"]))
+
+	return nil
+}
+
+// detectInstallation checks the game's installation. Settings listed in keep are not rewritten for Steam games.
+func (c *Core) detectInstallation(gameID GameID, apps map[string]SteamApp, keep map[string]bool) error {
+	info, err := c.GameTable.GameInfo(gameID)
+	if err != nil {
+		return err
+	}
+
+	if info.SteamAppID == "" {
+		return c.detectPathInstallation(gameID)
+	}
+
+	app, installed := apps[info.SteamAppID]
+	if !installed {
+		c.Installs.Insert(gameID, InstallInfo{Installed: false, Source: InstallSourceSteam})
+		return nil
+	}
+
+	settings, err := c.ResolvedSettings(gameID)
+	if err != nil {
+		return err
+	}
+
+	path, workdir := SteamGamePaths(app, settings)
+	if keep["path"] {
+		path = settings["path"]
+	} else if path != "" {
+		c.GameTable.SetSetting(gameID, "path", path)
+	}
+	if keep["workdir"] {
+		workdir = settings["workdir"]
+	} else {
+		c.GameTable.SetSetting(gameID, "workdir", workdir)
+	}
+
+	c.Installs.Insert(gameID, InstallInfo{Installed: true, Source: InstallSourceSteam, Path: path, Workdir: workdir, Executable: isExecutableFile(path)})
+
+	return nil
+}
+
+// DetectInstallations checks the selected games' installations. Steam games are looked up in local Steam libraries and get their path and workdir settings fixed up, other games are resolved against workdir and $PATH.
+func (c *Core) DetectInstallations(gameIDs []GameID) map[GameID]error {
+	return c.DetectNewInstallations(gameIDs, nil)
+}
+
+// DetectNewInstallations works like DetectInstallations for freshly created games, keeping the settings that their creator set explicitly.
+func (c *Core) DetectNewInstallations(gameIDs []GameID, keep map[GameID]map[string]bool) map[GameID]error {
+	apps := ScanSteamLibraries(c.SteamRoots)
+
+	output := make(map[GameID]error, len(gameIDs))
+	for _, gameID := range gameIDs {
+		output[gameID] = c.detectInstallation(gameID, apps, keep[gameID])
+	}
+
+	return output
+}
+
+// storedSettingKeys lists the settings each game has stored. Games whose settings were copied, restored or imported keep them through DetectNewInstallations.
+func (c *Core) storedSettingKeys(gameIDs []GameID) map[GameID]map[string]bool {
+	output := make(map[GameID]map[string]bool, len(gameIDs))
+	for _, gameID := range gameIDs {
+		settings, _ := c.GameTable.Settings(gameID)
+		keys := make(map[string]bool, len(settings))
+		for k := range settings {
+			keys[k] = true
+		}
+		output[gameID] = keys
+	}
+
+	return output
+}
+
 // StartGame executes launcher pattern for selected game and server.
 func (c *Core) StartGame(gameID GameID, server string, password string) error {
 	if gameID == "" {
@@ -107,12 +298,14 @@ func (c *Core) StartGame(gameID GameID, server string, password string) error {
 	return nil
 }
 
-// StartCore creates the core instance.
-func StartCore() *Core {
-	c := Core{GameTable: MakeMemGameTable(), Proxies: MakeProxyCollection(), Adapters: MakeAdapterCollection()}
+// StartCore creates the core instance on top of the given game table.
+func StartCore(gameTable GameTable) *Core {
+	c := Core{GameTable: gameTable, currentCatalog: MakeCatalog(), Globals: MakeGlobalSettings(), Proxies: MakeProxyCollection(), Adapters: MakeAdapterCollection(), Installs: MakeInstallCollection(), History: MakeHistoryCollection(), Stats: MakeStatsCache(), Players: MakePlayerIndex(), Buddies: MakeBuddyList(), Alerts: MakeAlertCollection(), Blocks: MakeBlockList(), Trash: MakeTrash(), SteamRoots: DefaultSteamRoots()}
 
 	c.Proxies.Insert(ProxyQStatOutput, GetQStatOutput)
+	c.Proxies.Describe(ProxyQStatOutput, QStatOutputInfo)
 	c.Adapters.Insert(AdapterQStatXML, AdaptQStatOutput)
+	c.Adapters.Describe(AdapterQStatXML, QStatXMLInfo)
 
 	return &c
 }
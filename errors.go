@@ -11,7 +11,47 @@ var errNoAdapter = errors.New("Specified adapter does not exist")
 var errInvalidID = errors.New("Invalid ID")
 var errInvalidGameID = errors.New("Please specify a valid game id")
 var errGameExists = errors.New("Specified game already exists in the database")
+var errGameBusy = errors.New("Specified game is being refreshed")
 var errNoSuchGame = errors.New("Specified game is not found in the database")
 var errUnknownGameID = errors.New("Specified game ID is not found in the database")
 var errinvalidIDList = errors.New("Please specify a list of valid game IDs")
 var errMalformedEntry = errors.New("Malformed server entry")
+var errMalformedVDF = errors.New("Malformed Steam KeyValues file")
+var errUnknownSchemaVersion = errors.New("Unsupported game table schema version")
+var errNoSuchIndex = errors.New("Server collection has no such index")
+var errNoSuchServer = errors.New("Specified server is not found")
+var errNoPlayerName = errors.New("Please specify a player name")
+var errInvalidMatchMode = errors.New("Unknown name match mode")
+var errBadPattern = errors.New("Malformed wildcard pattern")
+var errWebhookStatus = errors.New("Webhook returned an error status")
+var errNoSuchBuddy = errors.New("Specified buddy is not found")
+var errNoBuddiesSpecified = errors.New("No buddies specified")
+var errNoSuchAlertRule = errors.New("Specified alert rule is not found")
+var errNoAlertRulesSpecified = errors.New("No alert rules specified")
+var errInvalidCooldown = errors.New("Cooldown must not be negative")
+var errNoSuchFavorite = errors.New("Specified favorite server is not found")
+var errNoFavoritesSpecified = errors.New("No favorite servers specified")
+var errInvalidHost = errors.New("Please specify a valid server address")
+var errInvalidBlockRule = errors.New("Block rules contain an invalid address, pattern or threshold")
+var errMalformedTOML = errors.New("Malformed TOML file")
+var errUnknownSetting = errors.New("Setting is not supported by the game")
+var errNoSettingKey = errors.New("Please specify a setting key")
+var errNoSuchSetting = errors.New("Specified setting is not set")
+var errSettingNotString = errors.New("Value must be a string")
+var errSettingNotBool = errors.New("Value must be a boolean")
+var errSettingNotInt = errors.New("Value must be an integer")
+var errSettingNotDuration = errors.New("Value must be a duration such as 30s or 5m")
+var errSettingNotPath = errors.New("Value must be a non-empty path without NUL characters")
+var errSettingNotURIList = errors.New("Value must be a list of URIs")
+var errInvalidTag = errors.New("Tags must be non-empty and contain no spaces")
+var errNoSuchGroup = errors.New("No games are in the specified group")
+var errNoSuchTrashEntry = errors.New("Specified trash entry is not found")
+var errNoTrashEntriesSpecified = errors.New("No trash entries specified")
+var errInvalidHistoryTier = errors.New("History tiers must have a positive capacity and a non-negative resolution")
+var errUnknownProxy = errors.New("Proxy is not registered")
+var errNoQueryTargets = errors.New("No master servers or favorites to query")
+var errUnsupportedMasterURI = errors.New("Master URI scheme is not supported by the proxy")
+var errMissingProxyOption = errors.New("Catalog proxy table lacks an option")
+var errUnknownAdapter = errors.New("Adapter is not registered")
+var errUnpairedProxy = errors.New("Proxy and adapter must be set together")
+var errIncompatibleAdapter = errors.New("Adapter does not work with the proxy")
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// ServerStatusUnlisted marks a favorite server that no query returned.
+const ServerStatusUnlisted = "UNLISTED"
+
+// FavoritesSetting is the setting through which proxies receive the space separated favorite addresses to query besides the masters.
+const FavoritesSetting = "favorites"
+
+// mergeFavorites flags favorite servers in the query result and appends the ones the result lacks.
+func mergeFavorites(servers []ServerData, favorites map[string]Favorite) []ServerData {
+	if len(favorites) == 0 {
+		return servers
+	}
+
+	seen := map[string]bool{}
+	for i, v := range servers {
+		if _, exists := favorites[v.Host]; exists {
+			servers[i].Favorite = true
+			seen[v.Host] = true
+		}
+	}
+
+	missing := make([]string, 0, len(favorites))
+	for host := range favorites {
+		if !seen[host] {
+			missing = append(missing, host)
+		}
+	}
+	sort.Strings(missing)
+
+	for _, host := range missing {
+		name := favorites[host].Label
+		if name == "" {
+			name = host
+		}
+		servers = append(servers, MakeServerData(ServerData{Host: host, Name: name, Status: ServerStatusUnlisted, Favorite: true}))
+	}
+
+	return servers
+}
+
+func favoriteHosts(favorites map[string]Favorite) string {
+	hosts := make([]string, 0, len(favorites))
+	for host := range favorites {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	return strings.Join(hosts, " ")
+}
+
+// SetFavorite stores the favorite and flags its server if it is already listed.
+func (c *Core) SetFavorite(gameID GameID, f Favorite) error {
+	if err := c.GameTable.SetFavorite(gameID, f); err != nil {
+		return err
+	}
+
+	servers, err := c.GameTable.FindServers(gameID, func(_ int, v ServerData) bool { return v.Host == f.Host })
+	if err != nil || len(servers) == 0 {
+		return err
+	}
+	for i := range servers {
+		servers[i].Favorite = true
+	}
+
+	return c.GameTable.InsertServers(gameID, servers)
+}
+
+// RemoveFavorite forgets the favorite. Its server stays listed unflagged unless only the favorite kept it there.
+func (c *Core) RemoveFavorite(gameID GameID, host string) error {
+	if err := c.GameTable.RemoveFavorite(gameID, host); err != nil {
+		return err
+	}
+
+	if _, err := c.GameTable.DeleteServers(gameID, func(_ int, v ServerData) bool { return v.Host == host && v.Status == ServerStatusUnlisted }); err != nil {
+		return err
+	}
+
+	servers, err := c.GameTable.FindServers(gameID, func(_ int, v ServerData) bool { return v.Host == host })
+	if err != nil || len(servers) == 0 {
+		return err
+	}
+	for i := range servers {
+		servers[i].Favorite = false
+	}
+
+	return c.GameTable.InsertServers(gameID, servers)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestMergeFavorites(t *testing.T) {
+	servers := []ServerData{MakeServerData(ServerData{Host: "a:1", Name: "A", Status: "UP"}), MakeServerData(ServerData{Host: "b:1", Name: "B", Status: "UP"})}
+	favorites := map[string]Favorite{"b:1": {Host: "b:1"}, "lan:1": {Host: "lan:1", Label: "LAN"}, "c:1": {Host: "c:1"}}
+
+	fixture := []ServerData{
+		MakeServerData(ServerData{Host: "a:1", Name: "A", Status: "UP"}),
+		MakeServerData(ServerData{Host: "b:1", Name: "B", Status: "UP", Favorite: true}),
+		MakeServerData(ServerData{Host: "c:1", Name: "c:1", Status: ServerStatusUnlisted, Favorite: true}),
+		MakeServerData(ServerData{Host: "lan:1", Name: "LAN", Status: ServerStatusUnlisted, Favorite: true}),
+	}
+
+	if result := mergeFavorites(servers, favorites); !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+	if result := favoriteHosts(favorites); result != "b:1 c:1 lan:1" {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, "b:1 c:1 lan:1", result))
+	}
+}
+
+func TestCoreFavorites(t *testing.T) {
+	c := StartCore(MakeMemGameTable())
+	c.GameTable.CreateGameEntry("q3a")
+	c.GameTable.InsertServers("q3a", []ServerData{MakeServerData(ServerData{Host: "a:1", Status: "UP"})})
+
+	c.SetFavorite("q3a", Favorite{Host: "a:1"})
+	c.SetFavorite("q3a", Favorite{Host: "lan:1"})
+	c.GameTable.InsertServers("q3a", []ServerData{MakeServerData(ServerData{Host: "lan:1", Name: "lan:1", Status: ServerStatusUnlisted, Favorite: true})})
+
+	servers, _ := c.GameTable.AllServers("q3a")
+	fixture := []ServerData{
+		MakeServerData(ServerData{Host: "a:1", Status: "UP", Favorite: true}),
+		MakeServerData(ServerData{Host: "lan:1", Name: "lan:1", Status: ServerStatusUnlisted, Favorite: true}),
+	}
+	if !reflect.DeepEqual(fixture, servers) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, servers))
+	}
+
+	c.RemoveFavorite("q3a", "a:1")
+	c.RemoveFavorite("q3a", "lan:1")
+
+	servers, _ = c.GameTable.AllServers("q3a")
+	fixture = []ServerData{MakeServerData(ServerData{Host: "a:1", Status: "UP"})}
+	if !reflect.DeepEqual(fixture, servers) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, servers))
+	}
+
+	if err := c.RemoveFavorite("q3a", "a:1"); err != errNoSuchFavorite {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errNoSuchFavorite.Error(), err))
+	}
+}
+
+func TestQueryFavorites(t *testing.T) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+echo '<qstat>'
+echo '<server type="Q3S" address="1.2.3.4:27960" status="UP"><name>Listed</name><map>q3dm17</map><numplayers>1</numplayers><maxplayers>16</maxplayers></server>'
+case "$*" in
+*"-q3s 10.0.0.2:27960"*) echo '<server type="Q3S" address="10.0.0.2:27960" status="UP"><name>LAN party</name><map>q3dm6</map><numplayers>4</numplayers><maxplayers>8</maxplayers></server>' ;;
+esac
+echo '</qstat>'
+`
+	if err := os.WriteFile(filepath.Join(dir, "qstat"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	catalog, err := ParseCatalog(
+		[]byte("[q3a]\nsettings = [\"master_uri\"]\n[q3a.proxy]\nmaster_type = \"Q3M\"\nserver_type = \"Q3S\"\n"),
+		[]byte("[q3a]\nmaster_uri = [\"master://master.example.com:27950\"]\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := StartCore(MakeMemGameTable())
+	c.SetCatalog(catalog)
+	c.GameTable.CreateGameEntry("q3a")
+	c.GameTable.SetGameInfo("q3a", GameInfo{Name: "Quake III Arena", Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML})
+	c.SetFavorite("q3a", Favorite{Host: "10.0.0.2:27960", Label: "LAN"})
+	c.SetFavorite("q3a", Favorite{Host: "10.0.0.3:27960", Label: "Offline"})
+
+	c.statMasterTarget("q3a", func(_ []ServerData, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	servers, _ := c.GameTable.AllServers("q3a")
+	got := map[string]ServerData{}
+	for _, v := range servers {
+		got[v.Host] = v
+	}
+	if v := got["10.0.0.2:27960"]; v.Status != "UP" || v.Name != "LAN party" || v.NumPlayers != 4 || !v.Favorite {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, "LAN party, UP, 4 players, favorite", v))
+	}
+	if v := got["10.0.0.3:27960"]; v.Status != ServerStatusUnlisted || !v.Favorite {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, ServerStatusUnlisted, v))
+	}
+	if _, exists := got["1.2.3.4:27960"]; !exists || len(servers) != 3 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, 3, len(servers)))
+	}
+}
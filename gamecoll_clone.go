@@ -0,0 +1,77 @@
+package main
+
+// keepCatalogBase points dst, a clone or the new ID of src, at src's catalog game so that it keeps the catalog defaults, setting list and tags.
+func (c *Core) keepCatalogBase(src GameID, dst GameID) error {
+	info, err := c.GameTable.GameInfo(dst)
+	if err != nil {
+		return err
+	}
+
+	base := info.CatalogBase(src)
+	if _, exists := c.catalog().Game(base); !exists {
+		return nil
+	}
+	if base == dst {
+		base = ""
+	}
+	if info.CatalogID == base {
+		return nil
+	}
+	info.CatalogID = base
+
+	return c.GameTable.SetGameInfo(dst, info)
+}
+
+// CloneGame creates dst as a variant of src with the same info, settings, favorites and block rules. Servers and their history are copied only if asked to.
+func (c *Core) CloneGame(src GameID, dst GameID, servers bool, history bool) error {
+	if dst == "" {
+		return errInvalidGameID
+	}
+	if err := c.GameTable.CloneGameEntry(src, dst, servers); err != nil {
+		return err
+	}
+	if err := c.keepCatalogBase(src, dst); err != nil {
+		return err
+	}
+
+	if history {
+		c.History.CopyGame(src, dst)
+	}
+	if servers {
+		c.ReindexPlayers(dst)
+	}
+	if err := c.Blocks.CopyGame(src, dst); err != nil {
+		return err
+	}
+
+	// The clone's stored settings are the ones copied from src.
+	ids := []GameID{dst}
+	return c.DetectNewInstallations(ids, c.storedSettingKeys(ids))[dst]
+}
+
+// RenameGame moves src with its servers, history, install state, block rules and alert rules to dst.
+func (c *Core) RenameGame(src GameID, dst GameID) error {
+	if dst == "" {
+		return errInvalidGameID
+	}
+	if err := c.GameTable.RenameGameEntry(src, dst); err != nil {
+		return err
+	}
+	if err := c.keepCatalogBase(src, dst); err != nil {
+		return err
+	}
+
+	c.History.RenameGame(src, dst)
+	if install, exists := c.Installs.Retrieve(src); exists {
+		c.Installs.Insert(dst, install)
+		c.Installs.Remove(src)
+	}
+	c.Stats.Remove(src)
+	c.Players.RemoveGame(src)
+	c.ReindexPlayers(dst)
+	if err := c.Blocks.RenameGame(src, dst); err != nil {
+		return err
+	}
+
+	return c.Alerts.RenameGame(src, dst)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func makeTestCloneCore(t *testing.T) *Core {
+	c := StartCore(MakeMemGameTable())
+	c.SteamRoots = nil
+	c.GameTable.CreateGameEntry("q3a")
+	c.GameTable.SetGameInfo("q3a", GameInfo{Name: "Quake III Arena", Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML})
+	c.GameTable.SetSetting("q3a", "master_uri", "master://master.ioquake3.org:27950")
+	servers := []ServerData{{Host: "1.2.3.4:27960", Name: "DM", Map: "q3dm17", Status: "UP", Players: []PlayerData{{Name: "Visor"}}}}
+	c.GameTable.InsertServers("q3a", servers)
+	c.History.Record("q3a", servers, time.Unix(1000, 0))
+	c.ReindexPlayers("q3a")
+	if err := c.Blocks.SetRules("q3a", BlockRules{OverMax: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Alerts.Upsert(AlertRule{ID: "full", GameID: "q3a", Condition: "numplayers >= 8"}); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestCloneGame(t *testing.T) {
+	c := makeTestCloneCore(t)
+
+	if err := c.CloneGame("q3a", "q3a-private", false, false); err != nil {
+		t.Fatal(err)
+	}
+	c.GameTable.SetSetting("q3a-private", "master_uri", "master://10.0.0.1:27950")
+
+	settings, _ := c.GameTable.Settings("q3a")
+	expectGameTableValue(t, "source settings", SettingsMap{"master_uri": "master://master.ioquake3.org:27950"}, settings)
+	info, _ := c.GameTable.GameInfo("q3a-private")
+	expectGameTableValue(t, "clone name", "Quake III Arena", info.Name)
+	expectGameTableValue(t, "clone block rules", BlockRules{OverMax: true}, c.Blocks.Rules("q3a-private"))
+	servers, _ := c.GameTable.AllServers("q3a-private")
+	expectGameTableValue(t, "clone servers", 0, len(servers))
+	_, exists := c.History.Retrieve("q3a-private", "1.2.3.4:27960", time.Time{})
+	expectGameTableValue(t, "clone history", false, exists)
+
+	if err := c.CloneGame("q3a", "q3a-copy", true, true); err != nil {
+		t.Fatal(err)
+	}
+	servers, _ = c.GameTable.AllServers("q3a-copy")
+	expectGameTableValue(t, "copy servers", 1, len(servers))
+	samples, _ := c.History.Retrieve("q3a-copy", "1.2.3.4:27960", time.Time{})
+	expectGameTableValue(t, "copy history", 1, len(samples))
+	c.History.Record("q3a-copy", servers, time.Unix(2000, 0))
+	samples, _ = c.History.Retrieve("q3a", "1.2.3.4:27960", time.Time{})
+	expectGameTableValue(t, "source history after copy change", 1, len(samples))
+	expectGameTableValue(t, "copy players", 2, len(c.Players.Search(PlayerQuery{Name: "Visor"})))
+
+	expectGameTableError(t, "CloneGame collision", errGameExists, c.CloneGame("q3a", "q3a-private", true, true))
+	expectGameTableError(t, "CloneGame missing", errUnknownGameID, c.CloneGame("q4", "q4-private", false, false))
+	expectGameTableError(t, "CloneGame without target", errInvalidGameID, c.CloneGame("q3a", "", false, false))
+}
+
+func TestCloneGameKeepsCopiedPaths(t *testing.T) {
+	root, _ := makeFakeSteamTree(t)
+
+	c := StartCore(MakeMemGameTable())
+	c.SteamRoots = []string{root}
+	c.GameTable.CreateGameEntry("csgo")
+	c.GameTable.SetGameInfo("csgo", GameInfo{Name: "Counter-Strike: Global Offensive", SteamAppID: "730"})
+	c.GameTable.SetSetting("csgo", "path", "/opt/csgo/csgo_linux")
+	c.GameTable.SetSetting("csgo", "workdir", "/opt/csgo")
+
+	if err := c.CloneGame("csgo", "csgo-lan", false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, _ := c.GameTable.Settings("csgo-lan")
+	expectGameTableValue(t, "clone settings", SettingsMap{"path": "/opt/csgo/csgo_linux", "workdir": "/opt/csgo"}, settings)
+}
+
+func TestRenameGame(t *testing.T) {
+	c := makeTestCloneCore(t)
+	c.Installs.Insert("q3a", InstallInfo{Installed: true})
+	c.GameTable.CreateGameEntry("openarena")
+
+	expectGameTableError(t, "RenameGame collision", errGameExists, c.RenameGame("q3a", "openarena"))
+	if err := c.RenameGame("q3a", "quake3"); err != nil {
+		t.Fatal(err)
+	}
+
+	expectGameTableValue(t, "old ID", false, c.GameTable.CheckGameEntry("q3a"))
+	servers, _ := c.GameTable.AllServers("quake3")
+	expectGameTableValue(t, "renamed servers", 1, len(servers))
+	_, exists := c.History.Retrieve("q3a", "1.2.3.4:27960", time.Time{})
+	expectGameTableValue(t, "old history", false, exists)
+	_, exists = c.History.Retrieve("quake3", "1.2.3.4:27960", time.Time{})
+	expectGameTableValue(t, "renamed history", true, exists)
+	install, _ := c.Installs.Retrieve("quake3")
+	expectGameTableValue(t, "renamed install", true, install.Installed)
+	expectGameTableValue(t, "old block rules", BlockRules{}, c.Blocks.Rules("q3a"))
+	expectGameTableValue(t, "renamed block rules", BlockRules{OverMax: true}, c.Blocks.Rules("quake3"))
+	expectGameTableValue(t, "renamed alert rule", GameID("quake3"), c.Alerts.All()[0].GameID)
+
+	players := c.Players.Search(PlayerQuery{Name: "Visor"})
+	expectGameTableValue(t, "renamed players", 1, len(players))
+	expectGameTableValue(t, "renamed player game", GameID("quake3"), players[0].GameID)
+}
+
+func TestCloneGameKeepsCatalog(t *testing.T) {
+	c := makeTestCatalogCore(t)
+	c.SteamRoots = nil
+	g := c.catalog().Games["tf"]
+	g.Tags = []string{"engine:source"}
+	c.catalog().Games["tf"] = g
+	c.ImportGameCollection([]byte(testGameLists), []byte(testGameSettings), false)
+	c.GameTable.ClearSettings("tf")
+
+	if err := c.CloneGame("tf", "tf-private", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetSetting("tf-private", "master_uri", []string{"master://10.0.0.1:27011"}); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, _ := c.ResolvedSettings("tf-private")
+	expectGameTableValue(t, "clone catalog default", "hl2_linux", settings["path"])
+	expectGameTableValue(t, "clone own master", "master://10.0.0.1:27011", settings["master_uri"])
+	if err := c.SetSetting("tf-private", "nickname", "Grunt"); err == nil {
+		t.Error("clone accepts a setting its catalog game does not list")
+	}
+	tags, _ := c.GameTags("tf-private")
+	expectGameTableValue(t, "clone catalog tags", []string{"engine:source"}, tags)
+
+	if err := c.RenameGame("tf-private", "tf-lan"); err != nil {
+		t.Fatal(err)
+	}
+	settings, _ = c.ResolvedSettings("tf-lan")
+	expectGameTableValue(t, "renamed clone catalog default", "hl2_linux", settings["path"])
+
+	if err := c.RenameGame("tf", "tf2"); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := c.GameTable.GameInfo("tf2")
+	expectGameTableValue(t, "renamed catalog game base", GameID("tf"), info.CatalogID)
+	if err := c.RenameGame("tf2", "tf"); err != nil {
+		t.Fatal(err)
+	}
+	info, _ = c.GameTable.GameInfo("tf")
+	expectGameTableValue(t, "catalog game renamed back", GameID(""), info.CatalogID)
+
+	exportedLists, exportedDefaults := c.ExportGameCollection()
+	other := makeTestCatalogCore(t)
+	other.ImportGameCollection(exportedLists, exportedDefaults, false)
+	info, _ = other.GameTable.GameInfo("tf-lan")
+	expectGameTableValue(t, "imported clone base", GameID("tf"), info.CatalogID)
+}
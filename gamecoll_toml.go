@@ -0,0 +1,334 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// gameListsKeyOrder and gameSettingsKeyOrder follow the layout of the asset files.
+var gameListsKeyOrder = []string{"name", "catalog_id", "proxy", "adapter", "launch_pattern", "steam_app_id", "settings", "tags"}
+var gameSettingsKeyOrder = []string{"path", "workdir", "master_uri", "query_timeout", "query_retries", "nickname", "steam_launch", "steam_path"}
+
+// Import actions.
+const (
+	ImportCreate    = "create"
+	ImportUpdate    = "update"
+	ImportUnchanged = "unchanged"
+)
+
+// FieldChange is a value an import changes. Nil Old means the field is added, nil New means it is removed.
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// GameImportChange describes what importing a game does, or would do on a dry run.
+type GameImportChange struct {
+	GameID   GameID                 `json:"game_id"`
+	Action   string                 `json:"action"`
+	Info     map[string]FieldChange `json:"info,omitempty"`
+	Settings map[string]FieldChange `json:"settings,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Fields   FieldErrors            `json:"fields,omitempty"`
+}
+
+// exportOrder lists games in catalog order followed by the others sorted by ID.
+func (c *Core) exportOrder() []GameID {
+	games := map[GameID]bool{}
+	for _, id := range c.GameTable.AllGames() {
+		games[id] = true
+	}
+
+	var output []GameID
+	for _, id := range c.catalog().Order {
+		if games[id] {
+			output = append(output, id)
+			delete(games, id)
+		}
+	}
+
+	var rest []GameID
+	for id := range games {
+		rest = append(rest, id)
+	}
+	sort.Slice(rest, func(i, j int) bool { return rest[i] < rest[j] })
+
+	return append(output, rest...)
+}
+
+// ExportGameCollection renders the games as game_lists.toml and default_game_settings.toml. Launch patterns, setting lists and proxy options come from the catalog, games outside of it list the settings they have.
+func (c *Core) ExportGameCollection() (gameLists []byte, defaults []byte) {
+	listTables := tomlTables{}
+	settingTables := tomlTables{}
+	var listOrder, settingOrder []string
+
+	for _, id := range c.exportOrder() {
+		info, err := c.GameTable.GameInfo(id)
+		if err != nil {
+			continue
+		}
+		settings, err := c.GameTable.Settings(id)
+		if err != nil {
+			continue
+		}
+
+		name := string(id)
+		table := map[string]interface{}{"name": info.Name, "proxy": string(info.Proxy), "adapter": string(info.Adapter)}
+		if info.SteamAppID != "" {
+			table["steam_app_id"] = info.SteamAppID
+		}
+		if info.CatalogID != "" {
+			table["catalog_id"] = string(info.CatalogID)
+		}
+
+		g, inCatalog := c.catalog().Game(info.CatalogBase(id))
+		if tags := exportedTags(g.Tags, info.Tags); inCatalog || len(tags) > 0 {
+			table["tags"] = tags
+		}
+		if inCatalog {
+			table["launch_pattern"] = g.LaunchPattern
+			table["settings"] = g.Settings
+		} else {
+			keys := make([]string, 0, len(settings))
+			for k := range settings {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			table["settings"] = keys
+		}
+		listTables[name] = table
+		listOrder = append(listOrder, name)
+
+		if inCatalog && len(g.ProxyOptions) > 0 {
+			listTables[name+".proxy"] = g.ProxyOptions
+			listOrder = append(listOrder, name+".proxy")
+		}
+
+		exported := typedSettings(settings)
+		if inCatalog {
+			// Catalog files write listed paths that are not set as empty strings.
+			for _, k := range g.Settings {
+				if _, set := exported[k]; !set && LookupSettingSpec(k).Type == SettingPath {
+					exported[k] = ""
+				}
+			}
+		}
+		settingTables[name] = exported
+		settingOrder = append(settingOrder, name)
+	}
+
+	return encodeTOML(listTables, listOrder, gameListsKeyOrder), encodeTOML(settingTables, settingOrder, gameSettingsKeyOrder)
+}
+
+// exportedTags lists the catalog tags of the game followed by its custom ones.
+func exportedTags(builtin []string, custom []string) []string {
+	output := append([]string{}, builtin...)
+	seen := map[string]bool{}
+	for _, tag := range builtin {
+		seen[tag] = true
+	}
+	for _, tag := range custom {
+		if !seen[tag] {
+			output = append(output, tag)
+		}
+	}
+
+	return output
+}
+
+// importedTags turns the tags listed for an imported game into its custom tags. The ones the catalog gives the game are left out, so exported catalog tags do not turn into custom ones.
+func (c *Core) importedTags(catalogID GameID, raw interface{}) ([]string, error) {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, errInvalidTag
+	}
+	tags := make([]string, 0, len(list))
+	for _, v := range list {
+		tag, ok := v.(string)
+		if !ok {
+			return nil, errInvalidTag
+		}
+		tags = append(tags, tag)
+	}
+
+	tags, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	g, _ := c.catalog().Game(catalogID)
+	builtin, _ := NormalizeTags(g.Tags)
+	output := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !hasTags(builtin, []string{tag}) {
+			output = append(output, tag)
+		}
+	}
+
+	return output, nil
+}
+
+func diffFields(old map[string]interface{}, patch map[string]interface{}) map[string]FieldChange {
+	output := map[string]FieldChange{}
+	for k, v := range patch {
+		prev, exists := old[k]
+		if exists && reflect.DeepEqual(prev, v) {
+			continue
+		}
+		output[k] = FieldChange{Old: prev, New: v}
+	}
+
+	return output
+}
+
+// planGameImport validates an imported game and works out the changes. The returned values are what has to be applied.
+func (c *Core) planGameImport(id GameID, listTable map[string]interface{}, settingTable map[string]interface{}) (change GameImportChange, info GameInfo, settings map[string]interface{}, err error) {
+	change = GameImportChange{GameID: id, Action: ImportUpdate}
+
+	exists := c.GameTable.CheckGameEntry(id)
+	if !exists {
+		if listTable == nil {
+			return change, info, nil, errUnknownGameID
+		}
+		change.Action = ImportCreate
+	} else {
+		info, _ = c.GameTable.GameInfo(id)
+	}
+
+	oldInfo := map[string]interface{}{"name": info.Name, "catalog_id": string(info.CatalogID), "proxy": string(info.Proxy), "adapter": string(info.Adapter), "steam_app_id": info.SteamAppID}
+	infoPatch := map[string]interface{}{}
+	for _, k := range []string{"name", "catalog_id", "proxy", "adapter", "steam_app_id"} {
+		if v, present := listTable[k].(string); present {
+			infoPatch[k] = v
+		}
+	}
+	if raw, present := listTable["tags"]; present {
+		catalogID := info.CatalogBase(id)
+		if v, present := infoPatch["catalog_id"]; present && v != "" {
+			catalogID = GameID(v.(string))
+		}
+		tags, err := c.importedTags(catalogID, raw)
+		if err != nil {
+			return change, info, nil, err
+		}
+		oldInfo["tags"] = append([]string{}, info.Tags...)
+		infoPatch["tags"] = tags
+	}
+	change.Info = diffFields(oldInfo, infoPatch)
+
+	if v, present := infoPatch["name"]; present {
+		info.Name = v.(string)
+	}
+	if v, present := infoPatch["catalog_id"]; present {
+		info.CatalogID = GameID(v.(string))
+	}
+	if v, present := infoPatch["proxy"]; present {
+		info.Proxy = ProxyID(v.(string))
+	}
+	if v, present := infoPatch["adapter"]; present {
+		info.Adapter = AdapterID(v.(string))
+	}
+	if v, present := infoPatch["steam_app_id"]; present {
+		info.SteamAppID = v.(string)
+	}
+	if v, present := infoPatch["tags"]; present {
+		info.Tags = v.([]string)
+	}
+
+	// Problems are collected per field like on create and update, and the game is not applied if there are any.
+	errs := FieldErrors{}
+	if err := c.CheckGameInfo(info); err != nil {
+		for k, v := range err.(FieldErrors) {
+			errs[k] = v
+		}
+	}
+	values, err := c.CheckSettings(id, settingTable)
+	if err != nil {
+		settingErrs, ok := err.(SettingErrors)
+		if !ok {
+			return change, info, nil, err
+		}
+		for k, v := range settingErrs {
+			errs["settings."+k] = v
+		}
+	}
+	if len(errs) > 0 {
+		return change, info, nil, errs
+	}
+
+	own, _ := c.GameTable.Settings(id)
+	oldSettings := typedSettings(own)
+	newSettings := map[string]interface{}{}
+	for k, v := range values {
+		if v == nil {
+			if _, exists := oldSettings[k]; exists {
+				newSettings[k] = nil
+			}
+			continue
+		}
+		newSettings[k], _ = DecodeSettingValue(LookupSettingSpec(k).Type, *v)
+	}
+	change.Settings = diffFields(oldSettings, newSettings)
+
+	if change.Action == ImportUpdate && len(change.Info) == 0 && len(change.Settings) == 0 {
+		change.Action = ImportUnchanged
+	}
+
+	return change, info, settingTable, nil
+}
+
+// ImportGameCollection creates and updates games from game_lists.toml and default_game_settings.toml contents. Settings are merged like on update: keys missing from the file are kept.
+// Only the game table is changed, catalog-only fields such as launch patterns are ignored. With dryRun nothing is applied and the result only reports what would change.
+func (c *Core) ImportGameCollection(gameLists []byte, defaults []byte, dryRun bool) ([]GameImportChange, error) {
+	listTables, listOrder, err := decodeTOML(gameLists)
+	if err != nil {
+		return nil, err
+	}
+	settingTables, settingOrder, err := decodeTOML(defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []GameID
+	seen := map[GameID]bool{}
+	for _, name := range append(listOrder, settingOrder...) {
+		id := GameID(name)
+		if name == "" || seen[id] {
+			continue
+		}
+		if _, isProxyTable := listTables[strings.TrimSuffix(name, ".proxy")]; isProxyTable && strings.HasSuffix(name, ".proxy") {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	output := make([]GameImportChange, 0, len(ids))
+	for _, id := range ids {
+		change, info, settings, err := c.planGameImport(id, listTables[string(id)], settingTables[string(id)])
+		if err == nil && !dryRun && change.Action != ImportUnchanged {
+			err = c.applyGameImport(id, change.Action == ImportCreate, info, settings)
+		}
+		if err != nil {
+			change.Error = err.Error()
+			change.Fields, _ = err.(FieldErrors)
+		}
+		output = append(output, change)
+	}
+
+	return output, nil
+}
+
+func (c *Core) applyGameImport(id GameID, create bool, info GameInfo, settings map[string]interface{}) error {
+	if create {
+		if err := c.GameTable.CreateGameEntry(id); err != nil {
+			return err
+		}
+	}
+	if err := c.GameTable.SetGameInfo(id, info); err != nil {
+		return err
+	}
+
+	return c.MergeSettings(id, settings)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+const testGameLists = `[minetest]
+name = "Minetest"
+proxy = "net_http"
+adapter = "minetest"
+launch_pattern = "minetest"
+settings = ["path", "master_uri", "nickname"]
+
+[tf]
+name = "Team Fortress 2"
+proxy = "qstat_output"
+adapter = "qstat_xml"
+launch_pattern = "hl2"
+steam_app_id = "440"
+settings = ["path", "workdir", "master_uri", "steam_launch"]
+[tf.proxy]
+master_type = "STM"
+server_type = "A2S"
+`
+
+const testGameSettings = `[minetest]
+path = "minetest"
+master_uri = ["http://servers.minetest.net"]
+nickname = "Player"
+
+[tf]
+path = "hl2_linux"
+workdir = ""
+master_uri = ["master://hl2master.steampowered.com:27011"]
+steam_launch = true
+`
+
+func makeTestCatalogCore(t *testing.T) *Core {
+	return makeTestCatalogCoreFrom(t, testGameLists)
+}
+
+// makeTestCatalogCoreFrom loads the catalog and registers stand-ins for the net_http proxy and the minetest adapter it uses.
+func makeTestCatalogCoreFrom(t *testing.T, gameLists string) *Core {
+	c := StartCore(MakeMemGameTable())
+	c.Proxies.Insert(ProxyNetHTTP, func(GameInfo, SettingsMap) ([]string, error) { return nil, nil })
+	c.Adapters.Insert("minetest", func([]string, GameInfo, SettingsMap) ([]ServerData, error) { return nil, nil })
+	c.Adapters.Describe("minetest", AdapterInfo{Proxies: []ProxyID{ProxyNetHTTP}})
+	catalog, err := ParseCatalog([]byte(gameLists), []byte(testGameSettings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetCatalog(catalog)
+
+	return c
+}
+
+func TestGameCollectionRoundTrip(t *testing.T) {
+	c := makeTestCatalogCore(t)
+	changes, err := c.ImportGameCollection([]byte(testGameLists), []byte(testGameSettings), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range changes {
+		if v.Action != ImportCreate || v.Error != "" {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, ImportCreate, v))
+		}
+	}
+	if len(changes) != len(c.catalog().Order) || len(c.GameTable.AllGames()) != len(c.catalog().Order) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, len(c.catalog().Order), len(changes)))
+	}
+
+	exportedLists, exportedDefaults := c.ExportGameCollection()
+
+	// The export describes the same catalog it was imported from.
+	exported, err := ParseCatalog(exportedLists, exportedDefaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.catalog(), exported) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, c.catalog(), exported))
+	}
+
+	other := makeTestCatalogCore(t)
+	if _, err := other.ImportGameCollection(exportedLists, exportedDefaults, false); err != nil {
+		t.Fatal(err)
+	}
+	otherLists, otherDefaults := other.ExportGameCollection()
+	if string(exportedLists) != string(otherLists) || string(exportedDefaults) != string(otherDefaults) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, string(exportedDefaults), string(otherDefaults)))
+	}
+
+	changes, _ = c.ImportGameCollection(exportedLists, exportedDefaults, true)
+	for _, v := range changes {
+		if v.Action != ImportUnchanged {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, ImportUnchanged, v))
+		}
+	}
+}
+
+func TestGameCollectionTagsRoundTrip(t *testing.T) {
+	gameLists := strings.Replace(testGameLists, `launch_pattern = "hl2"`, "launch_pattern = \"hl2\"\ntags = [\"engine:source\"]", 1)
+	c := makeTestCatalogCoreFrom(t, gameLists)
+	c.ImportGameCollection([]byte(gameLists), []byte(testGameSettings), false)
+	c.SetGameTags("tf", []string{"LAN", "engine:source"})
+	c.GameTable.CreateGameEntry("custom")
+	c.SetGameTags("custom", []string{"mine"})
+
+	exportedLists, exportedDefaults := c.ExportGameCollection()
+
+	other := makeTestCatalogCoreFrom(t, gameLists)
+	changes, err := other.ImportGameCollection(exportedLists, exportedDefaults, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range changes {
+		if v.Error != "" {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, "", v))
+		}
+	}
+	for id, fixture := range map[GameID][]string{"tf": {"engine:source", "lan"}, "custom": {"mine"}, "minetest": {}} {
+		result, _ := other.GameTags(id)
+		expectGameTableValue(t, "GameTags of "+string(id), fixture, result)
+	}
+	info, _ := other.GameTable.GameInfo("tf")
+	expectGameTableValue(t, "custom tags of tf", []string{"lan"}, info.Tags)
+
+	changes, _ = c.ImportGameCollection([]byte("[tf]\ntags = [\"bad tag\"]\n"), nil, true)
+	expectGameTableValue(t, "invalid tag", errInvalidTag.Error(), changes[0].Error)
+}
+
+func TestGameCollectionImportDryRun(t *testing.T) {
+	c := makeTestCatalogCore(t)
+	c.GameTable.CreateGameEntry("minetest")
+	c.GameTable.SetGameInfo("minetest", GameInfo{Name: "Minetest"})
+	c.GameTable.SetSetting("minetest", "path", "minetest")
+	c.GameTable.SetSetting("minetest", "nickname", "Player")
+
+	gameLists := []byte("[minetest]\nname = \"Minetest 5\"\n\n[custom]\nname = \"Custom\"\nproxy = \"qstat_output\"\nadapter = \"qstat_xml\"\n")
+	defaults := []byte("[minetest]\nnickname = \"Guest\"\nmaster_uri = [\"http://servers.minetest.net\"]\n\n[csgo]\npath = \"csgo\"\n\n[custom]\nbogus = 1\n")
+
+	changes, err := c.ImportGameCollection(gameLists, defaults, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customErrs := FieldErrors{"settings.bogus": errSettingNotString.Error()}
+	fixture := []GameImportChange{
+		{
+			GameID:   "minetest",
+			Action:   ImportUpdate,
+			Info:     map[string]FieldChange{"name": {"Minetest", "Minetest 5"}},
+			Settings: map[string]FieldChange{"nickname": {"Player", "Guest"}, "master_uri": {nil, []string{"http://servers.minetest.net"}}},
+		},
+		{GameID: "custom", Action: ImportCreate, Info: map[string]FieldChange{"name": {"", "Custom"}, "proxy": {"", "qstat_output"}, "adapter": {"", "qstat_xml"}}, Error: customErrs.Error(), Fields: customErrs},
+		{GameID: "csgo", Action: ImportUpdate, Error: errUnknownGameID.Error()},
+	}
+	if !reflect.DeepEqual(fixture, changes) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, changes))
+	}
+
+	expectGameTableSettings(t, c.GameTable, "minetest", SettingsMap{"path": "minetest", "nickname": "Player"})
+	expectGameTableValue(t, "CheckGameEntry after dry run", false, c.GameTable.CheckGameEntry("custom"))
+
+	c.ImportGameCollection(gameLists, defaults, false)
+	expectGameTableSettings(t, c.GameTable, "minetest", SettingsMap{"path": "minetest", "nickname": "Guest", "master_uri": "http://servers.minetest.net"})
+	info, _ := c.GameTable.GameInfo("minetest")
+	expectGameTableValue(t, "GameInfo after import", "Minetest 5", info.Name)
+}
+
+func TestGameCollectionImportChecksProxyAndAdapter(t *testing.T) {
+	c := makeTestCatalogCore(t)
+	c.ImportGameCollection([]byte(testGameLists), []byte(testGameSettings), false)
+
+	changes, err := c.ImportGameCollection([]byte("[q3a]\nname = \"Quake III Arena\"\nproxy = \"bogus\"\nadapter = \"qstat_xml\"\n\n[tf]\nadapter = \"minetest\"\n"), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := map[GameID]FieldErrors{
+		"q3a": {"proxy": fmt.Sprintf("%v: bogus", errUnknownProxy)},
+		"tf":  {"adapter": fmt.Sprintf("%v: minetest cannot parse qstat_output", errIncompatibleAdapter)},
+	}
+	result := map[GameID]FieldErrors{}
+	for _, v := range changes {
+		result[v.GameID] = v.Fields
+	}
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+
+	expectGameTableValue(t, "CheckGameEntry of rejected game", false, c.GameTable.CheckGameEntry("q3a"))
+	info, _ := c.GameTable.GameInfo("tf")
+	expectGameTableValue(t, "adapter of rejected update", AdapterQStatXML, info.Adapter)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skybon/semaphore"
+)
+
+// ServerSample is a single point of server history. Samples in downsampled tiers stand for several refreshes: NumPlayers is their average and Up is set if the server was up in any of them.
+type ServerSample struct {
+	Time       time.Time `json:"time"`
+	NumPlayers int       `json:"numplayers"`
+	Map        string    `json:"map"`
+	Up         bool      `json:"up"`
+	Samples    int       `json:"samples"`
+}
+
+func (s ServerSample) merge(o ServerSample) ServerSample {
+	total := s.Samples + o.Samples
+	s.NumPlayers = (s.NumPlayers*s.Samples + o.NumPlayers*o.Samples + total/2) / total
+	s.Samples = total
+	s.Up = s.Up || o.Up
+	if o.Map != "" {
+		s.Map = o.Map
+	}
+
+	return s
+}
+
+type sampleRing struct {
+	data  []ServerSample
+	start int
+	size  int
+}
+
+func (r *sampleRing) full() bool { return r.size == len(r.data) }
+
+func (r *sampleRing) push(v ServerSample) {
+	r.data[(r.start+r.size)%len(r.data)] = v
+	r.size++
+}
+
+func (r *sampleRing) popOldest() ServerSample {
+	v := r.data[r.start]
+	r.start = (r.start + 1) % len(r.data)
+	r.size--
+
+	return v
+}
+
+func (r *sampleRing) newest() *ServerSample {
+	if r.size == 0 {
+		return nil
+	}
+
+	return &r.data[(r.start+r.size-1)%len(r.data)]
+}
+
+func (r *sampleRing) appendTo(output []ServerSample, since time.Time) []ServerSample {
+	for i := 0; i < r.size; i++ {
+		v := r.data[(r.start+i)%len(r.data)]
+		if !v.Time.Before(since) {
+			output = append(output, v)
+		}
+	}
+
+	return output
+}
+
+// HistoryTier describes one level of server history. Tier 0 keeps raw samples, the next tiers merge samples that fall into the same Resolution-wide bucket.
+type HistoryTier struct {
+	Resolution time.Duration
+	Capacity   int
+}
+
+// DefaultHistoryTiers keep every refresh for the last hour or so, then 5 minute buckets for 3 hours and 30 minute buckets for a day.
+var DefaultHistoryTiers = []HistoryTier{{0, 60}, {5 * time.Minute, 36}, {30 * time.Minute, 48}}
+
+// ServerHistory is a bounded time series of a single server.
+type ServerHistory struct {
+	tiers  []HistoryTier
+	rings  []sampleRing
+	lastUp time.Time
+}
+
+func (h *ServerHistory) push(tier int, v ServerSample) {
+	if tier >= len(h.rings) {
+		return
+	}
+
+	r := &h.rings[tier]
+	if resolution := h.tiers[tier].Resolution; resolution > 0 {
+		v.Time = v.Time.Truncate(resolution)
+		if newest := r.newest(); newest != nil && newest.Time.Equal(v.Time) {
+			*newest = newest.merge(v)
+			return
+		}
+	}
+
+	if r.full() {
+		h.push(tier+1, r.popOldest())
+	}
+	r.push(v)
+}
+
+// Add appends a new sample to the history.
+func (h *ServerHistory) Add(v ServerSample) {
+	if v.Samples == 0 {
+		v.Samples = 1
+	}
+	if v.Up {
+		h.lastUp = v.Time
+	}
+
+	h.push(0, v)
+}
+
+// Samples returns all samples not older than since, oldest first.
+func (h *ServerHistory) Samples(since time.Time) []ServerSample {
+	var output []ServerSample
+	for i := len(h.rings) - 1; i >= 0; i-- {
+		output = h.rings[i].appendTo(output, since)
+	}
+
+	return output
+}
+
+// validateHistoryTiers rejects tiers that cannot hold samples.
+func validateHistoryTiers(tiers []HistoryTier) error {
+	if len(tiers) == 0 {
+		return errInvalidHistoryTier
+	}
+	for _, tier := range tiers {
+		if tier.Capacity <= 0 || tier.Resolution < 0 {
+			return errInvalidHistoryTier
+		}
+	}
+
+	return nil
+}
+
+// MakeServerHistory creates an empty history. Every tier must have a positive capacity.
+func MakeServerHistory(tiers []HistoryTier) (*ServerHistory, error) {
+	if err := validateHistoryTiers(tiers); err != nil {
+		return nil, err
+	}
+
+	h := &ServerHistory{tiers: tiers, rings: make([]sampleRing, len(tiers))}
+	for i, tier := range tiers {
+		h.rings[i].data = make([]ServerSample, tier.Capacity)
+	}
+
+	return h, nil
+}
+
+func isServerUp(v ServerData) bool {
+	switch strings.ToUpper(v.Status) {
+	case "DOWN", "TIMEOUT", "ERROR", "HOSTNOTFOUND", ServerStatusUnlisted:
+		return false
+	}
+
+	return true
+}
+
+// HistoryCollection holds the history of every server of every game. Servers that have not been up for Retention are forgotten.
+type HistoryCollection struct {
+	Retention time.Duration
+
+	tiers     []HistoryTier
+	data      map[GameID]map[string]*ServerHistory
+	semaphore semaphore.Semaphore
+}
+
+// Record adds a sample for every server of a refresh. Known servers that are missing from the refresh are recorded as down.
+func (c *HistoryCollection) Record(gameID GameID, servers []ServerData, t time.Time) {
+	c.semaphore.Exec(func() {
+		game, exists := c.data[gameID]
+		if !exists {
+			game = map[string]*ServerHistory{}
+			c.data[gameID] = game
+		}
+
+		seen := make(map[string]bool, len(servers))
+		for _, v := range servers {
+			h, exists := game[v.Host]
+			if !exists {
+				var err error
+				if h, err = MakeServerHistory(c.tiers); err != nil {
+					return
+				}
+				game[v.Host] = h
+			}
+			h.Add(ServerSample{Time: t, NumPlayers: v.NumPlayers, Map: v.Map, Up: isServerUp(v)})
+			seen[v.Host] = true
+		}
+
+		for host, h := range game {
+			if seen[host] {
+				continue
+			}
+			if t.Sub(h.lastUp) > c.Retention {
+				delete(game, host)
+				continue
+			}
+			h.Add(ServerSample{Time: t, Up: false})
+		}
+	})
+}
+
+// Retrieve returns the server's samples not older than since.
+func (c *HistoryCollection) Retrieve(gameID GameID, host string, since time.Time) (output []ServerSample, exists bool) {
+	c.semaphore.Exec(func() {
+		var h *ServerHistory
+		h, exists = c.data[gameID][host]
+		if exists {
+			output = h.Samples(since)
+		}
+	})
+
+	return output, exists
+}
+
+func (h *ServerHistory) clone() *ServerHistory {
+	output := &ServerHistory{tiers: h.tiers, rings: make([]sampleRing, len(h.rings)), lastUp: h.lastUp}
+	for i, r := range h.rings {
+		output.rings[i] = sampleRing{data: append([]ServerSample{}, r.data...), start: r.start, size: r.size}
+	}
+
+	return output
+}
+
+// CopyGame replaces the history of dst with a copy of src's.
+func (c *HistoryCollection) CopyGame(src GameID, dst GameID) {
+	c.semaphore.Exec(func() {
+		game := make(map[string]*ServerHistory, len(c.data[src]))
+		for host, h := range c.data[src] {
+			game[host] = h.clone()
+		}
+		c.data[dst] = game
+	})
+}
+
+// RenameGame moves the history of src to dst.
+func (c *HistoryCollection) RenameGame(src GameID, dst GameID) {
+	c.semaphore.Exec(func() {
+		if game, exists := c.data[src]; exists {
+			c.data[dst] = game
+			delete(c.data, src)
+		}
+	})
+}
+
+func (c *HistoryCollection) RemoveGame(gameID GameID) {
+	c.semaphore.Exec(func() {
+		delete(c.data, gameID)
+	})
+}
+
+// SetTiers changes the tiers of the servers recorded from now on. Existing histories keep their tiers.
+func (c *HistoryCollection) SetTiers(tiers []HistoryTier) (err error) {
+	if err = validateHistoryTiers(tiers); err != nil {
+		return err
+	}
+	c.semaphore.Exec(func() { c.tiers = tiers })
+
+	return nil
+}
+
+func MakeHistoryCollection() *HistoryCollection {
+	return &HistoryCollection{tiers: DefaultHistoryTiers, Retention: 24 * time.Hour, data: map[GameID]map[string]*ServerHistory{}, semaphore: semaphore.MakeSemaphore(1)}
+}
+
+// WriteHistoryCSV exports samples with a header row.
+func WriteHistoryCSV(w io.Writer, samples []ServerSample) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "numplayers", "map", "up", "samples"})
+	for _, v := range samples {
+		cw.Write([]string{v.Time.UTC().Format(time.RFC3339), strconv.Itoa(v.NumPlayers), v.Map, strconv.FormatBool(v.Up), strconv.Itoa(v.Samples)})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
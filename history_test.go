@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skybon/goutil"
+)
+
+func TestServerHistoryDownsampling(t *testing.T) {
+	h, err := MakeServerHistory([]HistoryTier{{0, 3}, {10 * time.Minute, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 9; i++ {
+		h.Add(ServerSample{Time: start.Add(time.Duration(i) * 4 * time.Minute), NumPlayers: i, Map: "q3dm17", Up: i != 1})
+	}
+
+	fixture := []ServerSample{
+		{Time: start.Add(10 * time.Minute), NumPlayers: 4, Map: "q3dm17", Up: true, Samples: 2},
+		{Time: start.Add(20 * time.Minute), NumPlayers: 5, Map: "q3dm17", Up: true, Samples: 1},
+		{Time: start.Add(24 * time.Minute), NumPlayers: 6, Map: "q3dm17", Up: true, Samples: 1},
+		{Time: start.Add(28 * time.Minute), NumPlayers: 7, Map: "q3dm17", Up: true, Samples: 1},
+		{Time: start.Add(32 * time.Minute), NumPlayers: 8, Map: "q3dm17", Up: true, Samples: 1},
+	}
+
+	result := h.Samples(time.Time{})
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+
+	result = h.Samples(start.Add(25 * time.Minute))
+	if !reflect.DeepEqual(fixture[3:], result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture[3:], result))
+	}
+}
+
+func TestServerHistoryInvalidTiers(t *testing.T) {
+	for _, tiers := range [][]HistoryTier{nil, {{0, 0}}, {{0, 3}, {time.Minute, -1}}, {{-time.Minute, 3}}} {
+		if _, err := MakeServerHistory(tiers); err != errInvalidHistoryTier {
+			t.Error(tiers, goutil.ErrorOutJSON(goutil.ErrMismatch, errInvalidHistoryTier, err))
+		}
+	}
+
+	c := MakeHistoryCollection()
+	if err := c.SetTiers([]HistoryTier{{0, 0}}); err != errInvalidHistoryTier {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errInvalidHistoryTier, err))
+	}
+	c.Record("q3a", []ServerData{{Host: "a:1", Status: "UP"}}, time.Now())
+	if _, exists := c.Retrieve("q3a", "a:1", time.Time{}); !exists {
+		t.Error("rejected tiers replaced the working ones")
+	}
+}
+
+func TestHistoryCollectionRecord(t *testing.T) {
+	c := MakeHistoryCollection()
+	c.Retention = time.Hour
+	start := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	c.Record("q3a", []ServerData{{Host: "a:1", Map: "q3dm17", NumPlayers: 4, Status: "UP"}, {Host: "b:1", Map: "q3dm6", Status: "TIMEOUT"}}, start)
+	c.Record("q3a", []ServerData{{Host: "a:1", Map: "q3dm6", NumPlayers: 2, Status: "UP"}}, start.Add(time.Minute))
+	c.Record("q3a", nil, start.Add(2*time.Hour))
+
+	fixture := []ServerSample{
+		{Time: start, NumPlayers: 4, Map: "q3dm17", Up: true, Samples: 1},
+		{Time: start.Add(time.Minute), NumPlayers: 2, Map: "q3dm6", Up: true, Samples: 1},
+	}
+	if _, exists := c.Retrieve("q3a", "a:1", time.Time{}); exists {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, false, exists))
+	}
+
+	c.Record("q3a", []ServerData{{Host: "a:1", Map: "q3dm17", NumPlayers: 4, Status: "UP"}}, start)
+	c.Record("q3a", []ServerData{{Host: "a:1", Map: "q3dm6", NumPlayers: 2, Status: "UP"}}, start.Add(time.Minute))
+	c.Record("q3a", nil, start.Add(2*time.Minute))
+	fixture = append(fixture, ServerSample{Time: start.Add(2 * time.Minute), Samples: 1})
+
+	result, exists := c.Retrieve("q3a", "a:1", time.Time{})
+	if !exists || !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+
+	var buf bytes.Buffer
+	WriteHistoryCSV(&buf, result[:1])
+	csvFixture := "time,numplayers,map,up,samples\n2016-05-01T12:00:00Z,4,q3dm17,true,1\n"
+	if buf.String() != csvFixture {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, csvFixture, buf.String()))
+	}
+}
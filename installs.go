@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/skybon/semaphore"
+)
+
+// InstallInfo describes whether and where a game is installed locally.
+type InstallInfo struct {
+	Installed  bool   `json:"installed"`
+	Source     string `json:"source"`
+	Path       string `json:"path"`
+	Workdir    string `json:"workdir"`
+	Executable bool   `json:"executable"`
+	Version    string `json:"version"`
+}
+
+const (
+	InstallSourceSteam = "steam"
+	InstallSourcePath  = "path"
+)
+
+var versionPattern = regexp.MustCompile(`\d+(?:\.\d+)+[a-z]?`)
+
+func isExecutableFile(path string) bool {
+	st, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return st.Mode().IsRegular() && st.Mode().Perm()&0111 != 0
+}
+
+// executableVersion guesses the version from the resolved file name, e.g. /usr/games/quake3 -> ioquake3-1.36. Running the binary itself could start the game, so it is never executed.
+func executableVersion(path string) string {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return ""
+	}
+
+	return versionPattern.FindString(filepath.Base(realPath))
+}
+
+// LookupGameExecutable resolves the game's path setting against its workdir and $PATH.
+func LookupGameExecutable(path string, workdir string) InstallInfo {
+	output := InstallInfo{Source: InstallSourcePath}
+
+	path = ExpandHome(path)
+	workdir = ExpandHome(workdir)
+	if path == "" {
+		return output
+	}
+
+	var candidates []string
+	switch {
+	case filepath.IsAbs(path):
+		candidates = []string{path}
+	case strings.ContainsRune(path, filepath.Separator):
+		if workdir != "" {
+			candidates = []string{filepath.Join(workdir, path)}
+		}
+	default:
+		if workdir != "" {
+			candidates = append(candidates, filepath.Join(workdir, path))
+		}
+		if lookPath, err := exec.LookPath(path); err == nil {
+			candidates = append(candidates, lookPath)
+		}
+	}
+
+	for _, candidate := range candidates {
+		st, err := os.Stat(candidate)
+		if err != nil || st.IsDir() {
+			continue
+		}
+
+		absPath, err := filepath.Abs(candidate)
+		if err != nil {
+			continue
+		}
+
+		output.Installed = true
+		output.Path = absPath
+		output.Workdir = workdir
+		output.Executable = isExecutableFile(absPath)
+		output.Version = executableVersion(absPath)
+		if output.Executable {
+			break
+		}
+	}
+
+	return output
+}
+
+// InstallCollection holds the result of the latest installation check of every game.
+type InstallCollection struct {
+	data      map[GameID]InstallInfo
+	semaphore semaphore.Semaphore
+}
+
+func (c *InstallCollection) Insert(k GameID, v InstallInfo) {
+	c.semaphore.Exec(func() {
+		c.data[k] = v
+	})
+}
+
+func (c *InstallCollection) Retrieve(k GameID) (v InstallInfo, exists bool) {
+	c.semaphore.Exec(func() {
+		v, exists = c.data[k]
+	})
+
+	return v, exists
+}
+
+func (c *InstallCollection) Remove(k GameID) {
+	c.semaphore.Exec(func() {
+		delete(c.data, k)
+	})
+}
+
+func MakeInstallCollection() *InstallCollection {
+	return &InstallCollection{data: make(map[GameID]InstallInfo), semaphore: semaphore.MakeSemaphore(1)}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestLookupGameExecutable(t *testing.T) {
+	binDir := t.TempDir()
+	workdir := t.TempDir()
+
+	writeTestFile(t, filepath.Join(binDir, "ioquake3-1.36"), "#!/bin/sh\n")
+	os.Chmod(filepath.Join(binDir, "ioquake3-1.36"), 0755)
+	os.Symlink(filepath.Join(binDir, "ioquake3-1.36"), filepath.Join(binDir, "quake3"))
+	writeTestFile(t, filepath.Join(workdir, "xonotic-sdl"), "")
+
+	t.Setenv("PATH", binDir)
+
+	fixtures := []struct {
+		Path    string
+		Workdir string
+		Result  InstallInfo
+	}{
+		{"quake3", "", InstallInfo{Installed: true, Source: InstallSourcePath, Path: filepath.Join(binDir, "quake3"), Executable: true, Version: "1.36"}},
+		{"xonotic-sdl", workdir, InstallInfo{Installed: true, Source: InstallSourcePath, Path: filepath.Join(workdir, "xonotic-sdl"), Workdir: workdir}},
+		{"openarena", workdir, InstallInfo{Source: InstallSourcePath}},
+		{"", "", InstallInfo{Source: InstallSourcePath}},
+	}
+
+	for _, fixture := range fixtures {
+		result := LookupGameExecutable(fixture.Path, fixture.Workdir)
+		if !reflect.DeepEqual(fixture.Result, result) {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture.Result, result))
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+// launchPatterns are the known ways of passing a server to a game's command line, keyed by the catalog's launch_pattern.
+var launchPatterns = map[string]string{
+	"quake":      "Quake engine games, joined with +connect and +password",
+	"hl2":        "Source engine games, joined with +connect and +password",
+	"minetest":   "Minetest, joined with --address, --port and --name",
+	"openttd":    "OpenTTD, joined with -n and -p",
+	"rigsofrods": "Rigs of Rods, joined with -joinserver",
+}
+
+// LaunchPatternExists tells whether the launch pattern is known.
+func LaunchPatternExists(pattern string) bool {
+	_, exists := launchPatterns[pattern]
+
+	return exists
+}
@@ -2,7 +2,13 @@ package main
 
 import (
 	"flag"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
 // APIVer is the current version of Obozrenie server API.
@@ -10,18 +16,86 @@ const APIVer = "0.1"
 const APIPrefix = "/" + APIVer
 
 const gameCollPrefix = APIPrefix + "/gamecoll"
+const settingsPrefix = APIPrefix + "/settings"
+const trashPrefix = APIPrefix + "/trash"
+const blockListPrefix = APIPrefix + "/blocklist"
+const favoritesPrefix = APIPrefix + "/favorites"
+const alertsPrefix = APIPrefix + "/alerts"
+const buddiesPrefix = APIPrefix + "/buddies"
+const serversPrefix = APIPrefix + "/servers"
 const systemPrefix = APIPrefix + "/system"
 
+func isFlagSet(name string) (set bool) {
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
 func main() {
 	var sAddr = flag.String("addr", ":16987", "Server address")
 	var authPass = flag.String("password", "", "Server access password")
+	var dbPath = flag.String("db", "", "Game table database file, games are kept in memory only if empty")
+	var snapshotPath = flag.String("snapshot", "", "Snapshot file the game table is restored from on start and saved to on shutdown")
+	var snapshotInterval = flag.Duration("snapshot-interval", 5*time.Minute, "Interval between periodic snapshots, 0 to save on shutdown only")
+	var stateDir = flag.String("state-dir", "", "Directory for global settings, buddy lists, alert rules, block rules, deleted games and other user state, kept in memory only if empty")
+	var catalogDir = flag.String("catalog", "assets", "Directory with game_lists.toml and default_game_settings.toml, the server starts with an empty catalog if the default one is missing")
+	var catalogWatch = flag.Duration("catalog-watch-interval", 5*time.Second, "Interval between checks of the catalog files for changes, 0 to reload on SIGHUP only")
+	var checkCatalog = flag.Bool("check-catalog", false, "Check the catalog against the registered proxies, adapters and launch patterns, print a report and exit")
+	var trashRetention = flag.Duration("trash-retention", DefaultTrashRetention, "How long deleted games can be restored")
+	var steamRoots = flag.String("steam-root", strings.Join(DefaultSteamRoots(), ","), "Comma-separated list of Steam installation directories")
 
 	flag.Parse()
 
+	if *checkCatalog {
+		if !checkCatalogDir(StartCore(MakeMemGameTable()), *catalogDir, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	var exitChan = make(chan struct{})
 
-	var actions = makeActionInstance(*authPass)
-	var sMux = makeServeMux(makeActionInstance(*authPass), exitChan)
+	var gameTable GameTable = MakeMemGameTable()
+	var fileTable *FileGameTable
+	if *dbPath != "" {
+		var err error
+		fileTable, err = OpenFileGameTable(*dbPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gameTable = fileTable
+	}
+
+	var actions = makeActionInstance(*authPass, gameTable)
+	if fileTable != nil {
+		fileTable.OnSaveError = actions.logGameTableSave
+	}
+	actions.core.SteamRoots = strings.Split(*steamRoots, ",")
+	catalog, err := LoadCatalog(*catalogDir)
+	if err != nil {
+		// Only a catalog that was asked for explicitly is required, the default relative path is missing unless run from the source tree.
+		if isFlagSet("catalog") {
+			log.Fatal(err)
+		}
+		log.Printf("Catalog not loaded, starting with an empty one: %v", err)
+		catalog = MakeCatalog()
+	}
+	actions.core.SetCatalog(catalog)
+	actions.enableCatalogReload(*catalogDir, *catalogWatch)
+	if *stateDir != "" {
+		if err := actions.enableState(*stateDir); err != nil {
+			log.Fatal(err)
+		}
+	}
+	actions.core.Trash.Retention = *trashRetention
+	if *snapshotPath != "" {
+		actions.enableSnapshots(*snapshotPath, *snapshotInterval)
+	}
+	var sMux = makeServeMux(actions, exitChan)
 
 	var server = &http.Server{
 		Addr:    *sAddr,
@@ -29,6 +103,26 @@ func main() {
 	}
 	go server.ListenAndServe()
 
-	<-exitChan
+	var sigChan = make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	var hupChan = make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			actions.reloadCatalog()
+		}
+	}()
+
+	select {
+	case <-exitChan:
+	case <-sigChan:
+	}
+	signal.Stop(hupChan)
+	if fileTable != nil {
+		if err := fileTable.Flush(); err != nil {
+			actions.logGameTableSave(err)
+		}
+	}
 	actions.cleanup()
 }
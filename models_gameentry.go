@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/skybon/semaphore"
@@ -113,12 +114,14 @@ type ServerData struct {
 	Name       string
 	Status     string
 	Map        string
+	GameType   string
 	Ping       int
 	Secure     bool
 	NumPlayers int
 	MaxPlayers int
 	Players    []PlayerData
 	Settings   ServerSettings
+	Favorite   bool
 }
 
 func MakeServerData(data ServerData) ServerData {
@@ -136,16 +139,27 @@ type ServerCollection interface {
 	Find(func(int, ServerData) bool) []ServerData
 	Insert([]ServerData) error
 	Delete(func(int, ServerData) bool) []ServerData
+	Replace([]ServerData) error
 }
 
 // ServerCollection represents server collection.
 type SimpleServerCollection struct {
-	semaphore semaphore.Semaphore
-	data      []ServerData
-	modDt     time.Time
+	lock  sync.RWMutex
+	data  []ServerData
+	modDt time.Time
+}
+
+func (c *SimpleServerCollection) safeExec(f func()) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	f()
 }
 
-func (c *SimpleServerCollection) safeExec(f func()) { c.semaphore.Exec(f) }
+func (c *SimpleServerCollection) safeRead(f func()) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	f()
+}
 
 func (c *SimpleServerCollection) bumpModDate() { c.modDt = time.Now() }
 
@@ -182,14 +196,21 @@ func (c *SimpleServerCollection) delete(f func(int, ServerData) bool) (output []
 	}
 
 	c.data = newData
+	if len(output) > 0 {
+		c.bumpModDate()
+	}
 
 	return output
 }
 
-func (c *SimpleServerCollection) ModDate() time.Time { return c.modDt }
+func (c *SimpleServerCollection) ModDate() (output time.Time) {
+	c.safeRead(func() { output = c.modDt })
+
+	return output
+}
 
 func (c *SimpleServerCollection) Find(f func(int, ServerData) bool) (output []ServerData) {
-	c.safeExec(func() { output = c.find(f) })
+	c.safeRead(func() { output = c.find(f) })
 
 	return output
 }
@@ -206,9 +227,19 @@ func (c *SimpleServerCollection) Delete(f func(int, ServerData) bool) (output []
 	return output
 }
 
+// Replace swaps the whole list for data in one step.
+func (c *SimpleServerCollection) Replace(data []ServerData) (err error) {
+	c.safeExec(func() {
+		c.data = make([]ServerData, 0, len(data))
+		err = c.insert(data)
+	})
+
+	return err
+}
+
 // MakeServerCollection creates an empty SimpleServerCollection.
 func MakeServerCollection() *SimpleServerCollection {
-	return &SimpleServerCollection{data: []ServerData{}, semaphore: semaphore.MakeSemaphore(1)}
+	return &SimpleServerCollection{data: []ServerData{}}
 }
 
 /*
@@ -228,21 +259,55 @@ const (
 
 // GameInfo is a structure that contains basic information desribing the game's internals. It is a programmer's responsibility to fill it in. User-definable settings should be placed in GameSettings instead.
 type GameInfo struct {
-	Name     string
-	Proxy    ProxyID
-	Adapter  AdapterID
-	StatFunc StatFunc
+	Name       string    `json:"name"`
+	Proxy      ProxyID   `json:"proxy"`
+	Adapter    AdapterID `json:"adapter"`
+	SteamAppID string    `json:"steam_app_id"`
+	Tags       []string  `json:"tags,omitempty"`
+	// CatalogID names the catalog game that clones and renamed games take their defaults, setting list and tags from.
+	CatalogID GameID   `json:"catalog_id,omitempty"`
+	StatFunc  StatFunc `json:"-"`
+}
+
+// CatalogBase returns the ID the game has in the catalog: CatalogID if set, its own ID otherwise.
+func (info GameInfo) CatalogBase(id GameID) GameID {
+	if info.CatalogID != "" {
+		return info.CatalogID
+	}
+
+	return id
+}
+
+// Favorite is a server that is always queried for the game, whether masters list it or not.
+type Favorite struct {
+	Host     string `json:"host"`
+	Label    string `json:"label"`
+	Password string `json:"password"`
+	Notes    string `json:"notes"`
+}
+
+// Validate checks that the favorite has a usable address.
+func (f Favorite) Validate() error {
+	if f.Host == "" {
+		return errInvalidHost
+	}
+	if host, _, _, err := ParseHostPort(f.Host); err != nil || host == "" {
+		return errInvalidHost
+	}
+
+	return nil
 }
 
 // GameEntry is a structure containing all information about a game.
 type GameEntry struct {
-	Info     GameInfo
-	Settings GameSettings
-	Servers  ServerCollection
-	Status   QueryStatus
+	Info      GameInfo
+	Settings  GameSettings
+	Favorites map[string]Favorite
+	Servers   ServerCollection
+	Status    QueryStatus
 }
 
 // MakeGameEntry creates an empty game entry.
 func MakeGameEntry() *GameEntry {
-	return &GameEntry{Settings: MakeSimpleGameSettings(), Servers: MakeServerCollection(), Status: QueryEmpty}
+	return &GameEntry{Settings: MakeSimpleGameSettings(), Favorites: map[string]Favorite{}, Servers: MakeIndexedServerCollection(ServerIndexMap, ServerIndexGameType, ServerIndexPlayers), Status: QueryEmpty}
 }
@@ -1,6 +1,10 @@
 package main
 
-import "github.com/skybon/semaphore"
+import (
+	"sort"
+	"sync"
+	"time"
+)
 
 type GameID string
 
@@ -13,6 +17,8 @@ type GameTable interface {
 	MatchGameEntries(func(GameID, *GameEntry) bool) []GameID
 	CreateGameEntry(GameID) error
 	RemoveGameEntry(GameID) error
+	CloneGameEntry(GameID, GameID, bool) error
+	RenameGameEntry(GameID, GameID) error
 
 	CopyGameEntry(GameID, bool) (*GameEntry, error)
 
@@ -29,347 +35,361 @@ type GameTable interface {
 	RemoveSetting(GameID, string) error
 	ClearSettings(GameID) error
 
+	Favorites(GameID) ([]Favorite, error)
+	SetFavorite(GameID, Favorite) error
+	RemoveFavorite(GameID, string) error
+
 	FindServers(GameID, func(int, ServerData) bool) ([]ServerData, error)
 	AllServers(GameID) ([]ServerData, error)
+	// InsertServers upserts by host: a server with a known host replaces the stored one in place, others are appended.
 	InsertServers(GameID, []ServerData) error
+	// ReplaceServers makes the list the game's servers in a single step, readers never see a partially replaced list.
+	ReplaceServers(GameID, []ServerData) error
 	DeleteServers(GameID, func(int, ServerData) bool) ([]ServerData, error)
 	ClearServers(GameID) error
+	ServersModDate(GameID) (time.Time, error)
+}
+
+// memGameSlot guards a single game entry so that operations on different games never wait for each other.
+type memGameSlot struct {
+	lock    sync.RWMutex
+	removed bool
+	entry   *GameEntry
 }
 
+// MemGameTable keeps game entries in memory. The table lock only guards the set of games, every entry has its own read/write lock.
 type MemGameTable struct {
-	semaphore semaphore.Semaphore
-	data      map[GameID]*GameEntry
+	lock sync.RWMutex
+	data map[GameID]*memGameSlot
 }
 
-func (t *MemGameTable) safeExec(f func()) { t.semaphore.Exec(f) }
+func (t *MemGameTable) slot(id GameID) (g *memGameSlot, exists bool) {
+	t.lock.RLock()
+	g, exists = t.data[id]
+	t.lock.RUnlock()
 
-func (t *MemGameTable) matchGames(f func(GameID, *GameEntry) bool) []GameID {
-	output := make([]GameID, 0, len(t.data))
-	for k, v := range t.data {
-		if f(k, v) {
-			output = append(output, k)
-		}
-	}
-
-	return output
+	return g, exists
 }
 
-func (t *MemGameTable) createGameEntry(id GameID) error {
-	_, exists := t.data[id]
-	if exists {
-		return errGameExists
+// readEntry runs f with the entry locked for reading.
+func (t *MemGameTable) readEntry(id GameID, f func(*GameEntry)) error {
+	g, exists := t.slot(id)
+	if !exists {
+		return errUnknownGameID
 	}
 
-	t.data[id] = MakeGameEntry()
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	if g.removed {
+		return errUnknownGameID
+	}
+	f(g.entry)
 
 	return nil
 }
 
-func (t *MemGameTable) removeGameEntry(id GameID) error {
-	_, exists := t.data[id]
+// writeEntry runs f with the entry locked for writing.
+func (t *MemGameTable) writeEntry(id GameID, f func(*GameEntry)) error {
+	g, exists := t.slot(id)
 	if !exists {
 		return errUnknownGameID
 	}
 
-	delete(t.data, id)
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.removed {
+		return errUnknownGameID
+	}
+	f(g.entry)
 
 	return nil
 }
 
-func (t *MemGameTable) gameEntrySnapshot(id GameID, servers bool) (*GameEntry, error) {
-	g, gexists := t.data[id]
-	if !gexists {
-		return nil, errUnknownGameID
-	}
+func (t *MemGameTable) slots() []GameID {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
 
-	e := MakeGameEntry()
-	e.Status = QueryEmpty
-	e.Info = g.Info
-	for k, v := range g.Settings.AllSettings() {
-		e.Settings.Set(k, v)
-	}
-	if servers {
-		e.Servers.Insert(g.Servers.Find(func(int, ServerData) bool { return true }))
+	output := make([]GameID, 0, len(t.data))
+	for k := range t.data {
+		output = append(output, k)
 	}
 
-	return e, nil
+	return output
 }
 
-func (t *MemGameTable) settings(id GameID) (output SettingsMap, err error) {
-	g, gexists := t.data[id]
-	if !gexists {
-		return nil, errUnknownGameID
+func (t *MemGameTable) MatchGameEntries(f func(GameID, *GameEntry) bool) []GameID {
+	ids := t.slots()
+	output := make([]GameID, 0, len(ids))
+	for _, id := range ids {
+		var matched bool
+		if t.readEntry(id, func(e *GameEntry) { matched = f(id, e) }) == nil && matched {
+			output = append(output, id)
+		}
 	}
 
-	output = g.Settings.AllSettings()
-
-	return output, nil
+	return output
 }
 
-func (t *MemGameTable) getSetting(id GameID, settingID string) (v string, exists bool, err error) {
-	g, gexists := t.data[id]
-	if !gexists {
-		return "", false, errUnknownGameID
-	}
+func (t *MemGameTable) AllGames() []GameID { return t.slots() }
 
-	v, exists = g.Settings.Get(settingID)
+func (t *MemGameTable) CheckGameEntry(id GameID) bool {
+	_, exists := t.slot(id)
 
-	return v, exists, err
+	return exists
 }
 
-func (t *MemGameTable) setSetting(id GameID, settingID string, v string) error {
-	g, exists := t.data[id]
-	if !exists {
-		return errUnknownGameID
+func (t *MemGameTable) CreateGameEntry(id GameID) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, exists := t.data[id]; exists {
+		return errGameExists
 	}
 
-	g.Settings.Set(settingID, v)
-	t.data[id] = g
+	t.data[id] = &memGameSlot{entry: MakeGameEntry()}
 
 	return nil
 }
 
-func (t *MemGameTable) removeSetting(id GameID, settingID string) error {
+// RemoveGameEntry drops the slot from the table first and marks it removed afterwards, so that waiting for a long write on the entry never blocks the table lock.
+func (t *MemGameTable) RemoveGameEntry(id GameID) error {
+	t.lock.Lock()
 	g, exists := t.data[id]
+	delete(t.data, id)
+	t.lock.Unlock()
+
 	if !exists {
 		return errUnknownGameID
 	}
 
-	g.Settings.Remove(settingID)
-	t.data[id] = g
+	g.lock.Lock()
+	g.removed = true
+	g.lock.Unlock()
 
 	return nil
 }
 
-func (t *MemGameTable) clearSettings(id GameID) error {
-	g, exists := t.data[id]
-	if !exists {
-		return errUnknownGameID
+// CloneGameEntry creates dst with src's info, settings and favorites, and with its servers if asked to.
+func (t *MemGameTable) CloneGameEntry(src GameID, dst GameID, servers bool) error {
+	e, err := t.CopyGameEntry(src, servers)
+	if err != nil {
+		return err
 	}
 
-	g.Settings.Clear()
-	t.data[id] = g
+	t.lock.Lock()
+	defer t.lock.Unlock()
 
-	return nil
-}
-
-func (t *MemGameTable) findServers(id GameID, f func(int, ServerData) bool) (output []ServerData, err error) {
-	g, exists := t.data[id]
-	if !exists {
-		return nil, errUnknownGameID
+	if _, exists := t.data[dst]; exists {
+		return errGameExists
 	}
 
-	return g.Servers.Find(f), nil
+	t.data[dst] = &memGameSlot{entry: e}
+
+	return nil
 }
 
-func (t *MemGameTable) insertServers(id GameID, data []ServerData) (err error) {
-	g, exists := t.data[id]
+// RenameGameEntry moves the entry with its servers to a new ID. Games that are being queried cannot be renamed. The old slot is marked removed, so calls that looked it up before the move fail as for a deleted game. The entry is locked before the table, so the table lock is never held while waiting for a write on the entry.
+func (t *MemGameTable) RenameGameEntry(src GameID, dst GameID) error {
+	g, exists := t.slot(src)
 	if !exists {
 		return errUnknownGameID
 	}
 
-	err = g.Servers.Insert(data)
-	t.data[id] = g
-
-	return err
-}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.removed {
+		return errUnknownGameID
+	}
+	if g.entry.Status == QueryWorking {
+		return errGameBusy
+	}
 
-func (t *MemGameTable) deleteServers(id GameID, f func(int, ServerData) bool) (deleted []ServerData, err error) {
-	g, exists := t.data[id]
-	if !exists {
-		return nil, errUnknownGameID
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.data[src] != g {
+		return errUnknownGameID
+	}
+	if _, exists := t.data[dst]; exists {
+		return errGameExists
 	}
-	deleted = g.Servers.Delete(f)
-	t.data[id] = g
 
-	return deleted, err
+	g.removed = true
+	t.data[dst] = &memGameSlot{entry: g.entry}
+	delete(t.data, src)
+
+	return nil
 }
 
-func (t *MemGameTable) MatchGameEntries(f func(GameID, *GameEntry) bool) (output []GameID) {
-	t.safeExec(func() { output = t.matchGames(f) })
+// CopyGameEntry returns a snapshot of the entry. Servers are copied after the entry lock is released, the collection guards itself.
+func (t *MemGameTable) CopyGameEntry(id GameID, servers bool) (*GameEntry, error) {
+	e := MakeGameEntry()
+	e.Status = QueryEmpty
 
-	return output
-}
+	var srcServers ServerCollection
+	err := t.readEntry(id, func(g *GameEntry) {
+		e.Info = g.Info
+		for k, v := range g.Settings.AllSettings() {
+			e.Settings.Set(k, v)
+		}
+		for k, v := range g.Favorites {
+			e.Favorites[k] = v
+		}
+		srcServers = g.Servers
+	})
+	if err != nil {
+		return nil, err
+	}
 
-func (t *MemGameTable) AllGames() (output []GameID) {
-	t.safeExec(func() { output = t.matchGames(func(GameID, *GameEntry) bool { return true }) })
+	if servers {
+		e.Servers.Insert(srcServers.Find(func(int, ServerData) bool { return true }))
+	}
 
-	return output
+	return e, nil
 }
 
-func (t *MemGameTable) CheckGameEntry(id GameID) (exists bool) {
-	t.safeExec(func() { _, exists = t.data[id] })
+func (t *MemGameTable) QueryStatus(id GameID) (output QueryStatus, err error) {
+	err = t.readEntry(id, func(g *GameEntry) { output = g.Status })
 
-	return exists
+	return output, err
 }
 
-func (t *MemGameTable) CreateGameEntry(id GameID) (err error) {
-	t.safeExec(func() { err = t.createGameEntry(id) })
-
-	return err
+func (t *MemGameTable) SetQueryStatus(id GameID, status QueryStatus) error {
+	return t.writeEntry(id, func(g *GameEntry) { g.Status = status })
 }
 
-func (t *MemGameTable) RemoveGameEntry(id GameID) (err error) {
-	t.safeExec(func() { err = t.removeGameEntry(id) })
+func (t *MemGameTable) TryLockQuery(id GameID) (success bool, err error) {
+	err = t.writeEntry(id, func(g *GameEntry) {
+		if g.Status != QueryWorking {
+			g.Status = QueryWorking
+			success = true
+		}
+	})
 
-	return err
+	return success, err
 }
 
-func (t *MemGameTable) CopyGameEntry(id GameID, servers bool) (e *GameEntry, err error) {
-	t.safeExec(func() { e, err = t.gameEntrySnapshot(id, servers) })
+func (t *MemGameTable) GameInfo(id GameID) (output GameInfo, err error) {
+	err = t.readEntry(id, func(g *GameEntry) { output = g.Info })
 
-	return e, err
+	return output, err
 }
 
-func (t *MemGameTable) queryStatus(id GameID) (output QueryStatus, err error) {
-	g, exists := t.data[id]
-	if !exists {
-		return output, errUnknownGameID
-	}
-
-	return g.Status, nil
+func (t *MemGameTable) SetGameInfo(id GameID, info GameInfo) error {
+	return t.writeEntry(id, func(g *GameEntry) { g.Info = info })
 }
 
-func (t *MemGameTable) QueryStatus(id GameID) (output QueryStatus, err error) {
-	t.safeExec(func() {
-		output, err = t.queryStatus(id)
-	})
+func (t *MemGameTable) Settings(id GameID) (output SettingsMap, err error) {
+	err = t.readEntry(id, func(g *GameEntry) { output = g.Settings.AllSettings() })
 
 	return output, err
 }
 
-func (t *MemGameTable) SetQueryStatus(id GameID, status QueryStatus) (err error) {
-	t.safeExec(func() {
-		err = func(id GameID, status QueryStatus) error {
-			g, exists := t.data[id]
-			if !exists {
-				return errUnknownGameID
-			}
+func (t *MemGameTable) GetSetting(id GameID, settingID string) (v string, exists bool, err error) {
+	err = t.readEntry(id, func(g *GameEntry) { v, exists = g.Settings.Get(settingID) })
 
-			g.Status = status
-			t.data[id] = g
-
-			return nil
-		}(id, status)
-	})
-
-	return err
+	return v, exists, err
 }
 
-func (t *MemGameTable) tryLockQuery(id GameID) (success bool, err error) {
-	g, exists := t.data[id]
-	if !exists {
-		return false, errUnknownGameID
-	}
+// Settings and servers guard themselves, so modifying them only needs the entry to be held for reading.
 
-	if g.Status == QueryWorking {
-		return false, nil
-	}
-
-	g.Status = QueryWorking
-
-	return true, nil
+func (t *MemGameTable) SetSetting(id GameID, settingID string, v string) error {
+	return t.readEntry(id, func(g *GameEntry) { g.Settings.Set(settingID, v) })
 }
 
-func (t *MemGameTable) TryLockQuery(id GameID) (success bool, err error) {
-	t.safeExec(func() { success, err = t.tryLockQuery(id) })
+func (t *MemGameTable) RemoveSetting(id GameID, settingID string) error {
+	return t.readEntry(id, func(g *GameEntry) { g.Settings.Remove(settingID) })
+}
 
-	return success, err
+func (t *MemGameTable) ClearSettings(id GameID) error {
+	return t.readEntry(id, func(g *GameEntry) { g.Settings.Clear() })
 }
 
-func (t *MemGameTable) GameInfo(id GameID) (output GameInfo, err error) {
-	t.safeExec(func() {
-		output, err = func(id GameID) (GameInfo, error) {
-			g, exists := t.data[id]
-			if !exists {
-				return GameInfo{}, errUnknownGameID
-			}
-
-			return g.Info, nil
-		}(id)
+// Favorites returns the game's favorite servers ordered by address.
+func (t *MemGameTable) Favorites(id GameID) (output []Favorite, err error) {
+	err = t.readEntry(id, func(g *GameEntry) {
+		output = make([]Favorite, 0, len(g.Favorites))
+		for _, v := range g.Favorites {
+			output = append(output, v)
+		}
 	})
+	sort.Slice(output, func(i, j int) bool { return output[i].Host < output[j].Host })
 
 	return output, err
 }
 
-func (t *MemGameTable) SetGameInfo(id GameID, info GameInfo) (err error) {
-	t.safeExec(func() {
-		err = func(id GameID, info GameInfo) error {
-			g, exists := t.data[id]
-			if !exists {
-				return errUnknownGameID
-			}
+// SetFavorite adds the favorite or replaces the one with the same address.
+func (t *MemGameTable) SetFavorite(id GameID, f Favorite) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
 
-			g.Info = info
-			t.data[id] = g
+	return t.writeEntry(id, func(g *GameEntry) { g.Favorites[f.Host] = f })
+}
 
-			return nil
-		}(id, info)
+func (t *MemGameTable) RemoveFavorite(id GameID, host string) (err error) {
+	writeErr := t.writeEntry(id, func(g *GameEntry) {
+		if _, exists := g.Favorites[host]; !exists {
+			err = errNoSuchFavorite
+			return
+		}
+		delete(g.Favorites, host)
 	})
+	if writeErr != nil {
+		return writeErr
+	}
 
 	return err
 }
 
-func (t *MemGameTable) Settings(id GameID) (output SettingsMap, err error) {
-	t.safeExec(func() { output, err = t.settings(id) })
-
-	return output, err
-}
-
-func (t *MemGameTable) GetSetting(id GameID, settingID string) (e string, exists bool, err error) {
-	t.safeExec(func() { e, exists, err = t.getSetting(id, settingID) })
+func (t *MemGameTable) FindServers(id GameID, f func(int, ServerData) bool) (output []ServerData, err error) {
+	var servers ServerCollection
+	if err = t.readEntry(id, func(g *GameEntry) { servers = g.Servers }); err != nil {
+		return nil, err
+	}
 
-	return e, exists, err
+	return servers.Find(f), nil
 }
 
-func (t *MemGameTable) SetSetting(id GameID, settingID string, v string) (err error) {
-	t.safeExec(func() { err = t.setSetting(id, settingID, v) })
-
-	return err
+func (t *MemGameTable) AllServers(id GameID) ([]ServerData, error) {
+	return t.FindServers(id, func(int, ServerData) bool { return true })
 }
 
-func (t *MemGameTable) RemoveSetting(id GameID, settingID string) (err error) {
-	t.safeExec(func() { err = t.removeSetting(id, settingID) })
+func (t *MemGameTable) InsertServers(id GameID, data []ServerData) (err error) {
+	readErr := t.readEntry(id, func(g *GameEntry) { err = g.Servers.Insert(data) })
+	if readErr != nil {
+		return readErr
+	}
 
 	return err
 }
 
-func (t *MemGameTable) ClearSettings(id GameID) (err error) {
-	t.safeExec(func() { err = t.clearSettings(id) })
+func (t *MemGameTable) ReplaceServers(id GameID, data []ServerData) (err error) {
+	writeErr := t.writeEntry(id, func(g *GameEntry) { err = g.Servers.Replace(data) })
+	if writeErr != nil {
+		return writeErr
+	}
 
 	return err
 }
 
-func (t *MemGameTable) FindServers(id GameID, f func(int, ServerData) bool) (output []ServerData, err error) {
-	t.safeExec(func() { output, err = t.findServers(id, f) })
+func (t *MemGameTable) DeleteServers(id GameID, f func(int, ServerData) bool) (deleted []ServerData, err error) {
+	err = t.readEntry(id, func(g *GameEntry) { deleted = g.Servers.Delete(f) })
 
-	return output, err
+	return deleted, err
 }
 
-func (t *MemGameTable) AllServers(id GameID) (output []ServerData, err error) {
-	t.safeExec(func() { output, err = t.findServers(id, func(int, ServerData) bool { return true }) })
-
-	return output, err
-}
-
-func (t *MemGameTable) InsertServers(id GameID, data []ServerData) (err error) {
-	t.safeExec(func() { err = t.insertServers(id, data) })
+func (t *MemGameTable) ClearServers(id GameID) error {
+	_, err := t.DeleteServers(id, func(int, ServerData) bool { return true })
 
 	return err
 }
 
-func (t *MemGameTable) DeleteServers(id GameID, f func(int, ServerData) bool) (deleted []ServerData, err error) {
-	t.safeExec(func() { deleted, err = t.deleteServers(id, f) })
-
-	return deleted, err
-}
-
-func (t *MemGameTable) ClearServers(id GameID) (err error) {
-	t.safeExec(func() { _, err = t.deleteServers(id, func(int, ServerData) bool { return true }) })
+func (t *MemGameTable) ServersModDate(id GameID) (output time.Time, err error) {
+	err = t.readEntry(id, func(g *GameEntry) { output = g.Servers.ModDate() })
 
-	return err
+	return output, err
 }
 
 func MakeMemGameTable() *MemGameTable {
-	return &MemGameTable{semaphore: semaphore.MakeSemaphore(1), data: map[GameID]*GameEntry{}}
+	return &MemGameTable{data: map[GameID]*memGameSlot{}}
 }
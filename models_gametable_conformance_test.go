@@ -0,0 +1,647 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/skybon/goutil"
+)
+
+// gameTableConstructor creates an empty GameTable for a single conformance check.
+type gameTableConstructor func(t *testing.T) GameTable
+
+// testGameTableConformance checks that the GameTable implementation behaves exactly like MemGameTable. Every backend should run it from its own test.
+func testGameTableConformance(t *testing.T, makeTable gameTableConstructor) {
+	checks := []struct {
+		Name  string
+		Check func(*testing.T, GameTable)
+	}{
+		{"CreateGameEntry", checkCreateGameEntry},
+		{"RemoveGameEntry", checkRemoveGameEntry},
+		{"CheckGameEntry", checkCheckGameEntry},
+		{"AllGames", checkAllGames},
+		{"MatchGameEntries", checkMatchGameEntries},
+		{"CopyGameEntry", checkCopyGameEntry},
+		{"CloneGameEntry", checkCloneGameEntry},
+		{"RenameGameEntry", checkRenameGameEntry},
+		{"QueryStatus", checkQueryStatus},
+		{"TryLockQuery", checkTryLockQuery},
+		{"GameInfo", checkGameInfo},
+		{"Settings", checkSettings},
+		{"Favorites", checkFavorites},
+		{"Servers", checkServers},
+		{"InsertServersUpsert", checkInsertServersUpsert},
+		{"ReplaceServers", checkReplaceServers},
+		{"ServersModDate", checkServersModDate},
+		{"UnknownGameID", checkUnknownGameID},
+		{"ConcurrentTryLockQuery", checkConcurrentTryLockQuery},
+		{"ConcurrentAccess", checkConcurrentAccess},
+	}
+
+	for _, check := range checks {
+		check := check
+		t.Run(check.Name, func(t *testing.T) { check.Check(t, makeTable(t)) })
+	}
+}
+
+func expectGameTableError(t *testing.T, op string, expectation error, result error) {
+	t.Helper()
+	if expectation != result {
+		t.Error(op, goutil.ErrorOutJSON(goutil.ErrMismatch, fmt.Sprint(expectation), fmt.Sprint(result)))
+	}
+}
+
+func expectGameTableValue(t *testing.T, op string, expectation interface{}, result interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expectation, result) {
+		t.Error(op, goutil.ErrorOutJSON(goutil.ErrMismatch, expectation, result))
+	}
+}
+
+func sortedGameIDs(ids []GameID) []GameID {
+	output := append([]GameID{}, ids...)
+	sort.Slice(output, func(i, j int) bool { return output[i] < output[j] })
+	return output
+}
+
+func makeTestServers(prefix string, n int) []ServerData {
+	output := make([]ServerData, 0, n)
+	for i := 0; i < n; i++ {
+		output = append(output, MakeServerData(ServerData{Host: fmt.Sprintf("%s:%d", prefix, 27960+i), Name: fmt.Sprintf("%s #%d", prefix, i), NumPlayers: i % 16, MaxPlayers: 16}))
+	}
+	return output
+}
+
+func checkCreateGameEntry(t *testing.T, table GameTable) {
+	expectGameTableError(t, "CreateGameEntry", nil, table.CreateGameEntry("q3a"))
+	expectGameTableError(t, "CreateGameEntry duplicate", errGameExists, table.CreateGameEntry("q3a"))
+
+	status, err := table.QueryStatus("q3a")
+	expectGameTableError(t, "QueryStatus", nil, err)
+	expectGameTableValue(t, "QueryStatus of new entry", QueryEmpty, status)
+
+	info, _ := table.GameInfo("q3a")
+	expectGameTableValue(t, "GameInfo of new entry", GameInfo{}, info)
+
+	settings, _ := table.Settings("q3a")
+	expectGameTableValue(t, "Settings of new entry", SettingsMap{}, settings)
+
+	servers, _ := table.AllServers("q3a")
+	expectGameTableValue(t, "AllServers of new entry", 0, len(servers))
+}
+
+func checkRemoveGameEntry(t *testing.T, table GameTable) {
+	expectGameTableError(t, "RemoveGameEntry missing", errUnknownGameID, table.RemoveGameEntry("q3a"))
+
+	table.CreateGameEntry("q3a")
+	table.SetSetting("q3a", "path", "quake3")
+	table.InsertServers("q3a", makeTestServers("q3a", 3))
+
+	expectGameTableError(t, "RemoveGameEntry", nil, table.RemoveGameEntry("q3a"))
+	expectGameTableValue(t, "CheckGameEntry after removal", false, table.CheckGameEntry("q3a"))
+	expectGameTableError(t, "RemoveGameEntry twice", errUnknownGameID, table.RemoveGameEntry("q3a"))
+
+	table.CreateGameEntry("q3a")
+	settings, _ := table.Settings("q3a")
+	expectGameTableValue(t, "Settings of recreated entry", SettingsMap{}, settings)
+	servers, _ := table.AllServers("q3a")
+	expectGameTableValue(t, "AllServers of recreated entry", 0, len(servers))
+}
+
+func checkCheckGameEntry(t *testing.T, table GameTable) {
+	expectGameTableValue(t, "CheckGameEntry missing", false, table.CheckGameEntry("q3a"))
+	table.CreateGameEntry("q3a")
+	expectGameTableValue(t, "CheckGameEntry", true, table.CheckGameEntry("q3a"))
+	expectGameTableValue(t, "CheckGameEntry other", false, table.CheckGameEntry("q3"))
+}
+
+func checkAllGames(t *testing.T, table GameTable) {
+	expectGameTableValue(t, "AllGames empty", 0, len(table.AllGames()))
+
+	for _, id := range []GameID{"xonotic", "q3a", "openarena"} {
+		table.CreateGameEntry(id)
+	}
+	table.RemoveGameEntry("xonotic")
+
+	expectGameTableValue(t, "AllGames", []GameID{"openarena", "q3a"}, sortedGameIDs(table.AllGames()))
+}
+
+func checkMatchGameEntries(t *testing.T, table GameTable) {
+	for _, id := range []GameID{"csgo", "q3a", "openarena"} {
+		table.CreateGameEntry(id)
+	}
+	table.SetGameInfo("csgo", GameInfo{Name: "Counter-Strike: Global Offensive", SteamAppID: "730"})
+	table.SetSetting("q3a", "path", "quake3")
+	table.SetSetting("openarena", "path", "openarena")
+
+	result := table.MatchGameEntries(func(id GameID, e *GameEntry) bool { return e.Info.SteamAppID != "" })
+	expectGameTableValue(t, "MatchGameEntries by info", []GameID{"csgo"}, sortedGameIDs(result))
+
+	result = table.MatchGameEntries(func(id GameID, e *GameEntry) bool {
+		_, exists := e.Settings.Get("path")
+		return exists
+	})
+	expectGameTableValue(t, "MatchGameEntries by settings", []GameID{"openarena", "q3a"}, sortedGameIDs(result))
+
+	result = table.MatchGameEntries(func(GameID, *GameEntry) bool { return false })
+	expectGameTableValue(t, "MatchGameEntries none", 0, len(result))
+}
+
+func checkCloneGameEntry(t *testing.T, table GameTable) {
+	expectGameTableError(t, "CloneGameEntry missing", errUnknownGameID, table.CloneGameEntry("q3a", "q3a-private", true))
+
+	info := GameInfo{Name: "Quake III Arena", Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML, Tags: []string{"lan"}}
+	servers := makeTestServers("q3a", 3)
+	table.CreateGameEntry("q3a")
+	table.SetGameInfo("q3a", info)
+	table.SetSetting("q3a", "path", "quake3")
+	table.SetFavorite("q3a", Favorite{Host: "1.2.3.4:27960", Label: "Home"})
+	table.InsertServers("q3a", servers)
+	table.SetQueryStatus("q3a", QueryReady)
+
+	expectGameTableError(t, "CloneGameEntry", nil, table.CloneGameEntry("q3a", "q3a-private", false))
+	cloneInfo, _ := table.GameInfo("q3a-private")
+	expectGameTableValue(t, "GameInfo of clone", info, cloneInfo)
+	settings, _ := table.Settings("q3a-private")
+	expectGameTableValue(t, "Settings of clone", SettingsMap{"path": "quake3"}, settings)
+	favorites, _ := table.Favorites("q3a-private")
+	expectGameTableValue(t, "Favorites of clone", []Favorite{{Host: "1.2.3.4:27960", Label: "Home"}}, favorites)
+	cloneServers, _ := table.AllServers("q3a-private")
+	expectGameTableValue(t, "AllServers of clone without servers", 0, len(cloneServers))
+	status, _ := table.QueryStatus("q3a-private")
+	expectGameTableValue(t, "QueryStatus of clone", QueryEmpty, status)
+
+	table.SetSetting("q3a-private", "path", "ioquake3")
+	settings, _ = table.Settings("q3a")
+	expectGameTableValue(t, "Settings of source after clone change", SettingsMap{"path": "quake3"}, settings)
+
+	expectGameTableError(t, "CloneGameEntry with servers", nil, table.CloneGameEntry("q3a", "q3a-copy", true))
+	cloneServers, _ = table.FindServers("q3a-copy", func(int, ServerData) bool { return true })
+	expectGameTableValue(t, "AllServers of clone with servers", servers, cloneServers)
+
+	expectGameTableError(t, "CloneGameEntry collision", errGameExists, table.CloneGameEntry("q3a", "q3a-private", true))
+	expectGameTableError(t, "CloneGameEntry onto itself", errGameExists, table.CloneGameEntry("q3a", "q3a", true))
+	settings, _ = table.Settings("q3a-private")
+	expectGameTableValue(t, "Settings of collided clone", SettingsMap{"path": "ioquake3"}, settings)
+}
+
+func checkRenameGameEntry(t *testing.T, table GameTable) {
+	expectGameTableError(t, "RenameGameEntry missing", errUnknownGameID, table.RenameGameEntry("q3a", "quake3"))
+
+	info := GameInfo{Name: "Quake III Arena", Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML}
+	servers := makeTestServers("q3a", 3)
+	table.CreateGameEntry("q3a")
+	table.CreateGameEntry("openarena")
+	table.SetGameInfo("q3a", info)
+	table.SetSetting("q3a", "path", "quake3")
+	table.InsertServers("q3a", servers)
+	table.SetQueryStatus("q3a", QueryReady)
+
+	expectGameTableError(t, "RenameGameEntry collision", errGameExists, table.RenameGameEntry("q3a", "openarena"))
+
+	table.TryLockQuery("q3a")
+	expectGameTableError(t, "RenameGameEntry while querying", errGameBusy, table.RenameGameEntry("q3a", "quake3"))
+	table.SetQueryStatus("q3a", QueryReady)
+
+	expectGameTableError(t, "RenameGameEntry", nil, table.RenameGameEntry("q3a", "quake3"))
+	expectGameTableValue(t, "AllGames after rename", []GameID{"openarena", "quake3"}, sortedGameIDs(table.AllGames()))
+	renamedInfo, _ := table.GameInfo("quake3")
+	expectGameTableValue(t, "GameInfo after rename", info, renamedInfo)
+	settings, _ := table.Settings("quake3")
+	expectGameTableValue(t, "Settings after rename", SettingsMap{"path": "quake3"}, settings)
+	renamedServers, _ := table.FindServers("quake3", func(int, ServerData) bool { return true })
+	expectGameTableValue(t, "Servers after rename", servers, renamedServers)
+	status, _ := table.QueryStatus("quake3")
+	expectGameTableValue(t, "QueryStatus after rename", QueryReady, status)
+	_, err := table.GameInfo("q3a")
+	expectGameTableError(t, "GameInfo of old ID", errUnknownGameID, err)
+
+	expectGameTableError(t, "CreateGameEntry with old ID", nil, table.CreateGameEntry("q3a"))
+}
+
+func checkCopyGameEntry(t *testing.T, table GameTable) {
+	_, err := table.CopyGameEntry("q3a", true)
+	expectGameTableError(t, "CopyGameEntry missing", errUnknownGameID, err)
+
+	info := GameInfo{Name: "Quake III Arena", Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML}
+	servers := makeTestServers("q3a", 5)
+
+	table.CreateGameEntry("q3a")
+	table.SetGameInfo("q3a", info)
+	table.SetSetting("q3a", "path", "quake3")
+	table.InsertServers("q3a", servers)
+	table.SetQueryStatus("q3a", QueryReady)
+
+	e, err := table.CopyGameEntry("q3a", false)
+	expectGameTableError(t, "CopyGameEntry", nil, err)
+	expectGameTableValue(t, "CopyGameEntry info", info, e.Info)
+	expectGameTableValue(t, "CopyGameEntry settings", SettingsMap{"path": "quake3"}, e.Settings.AllSettings())
+	expectGameTableValue(t, "CopyGameEntry without servers", 0, len(e.Servers.Find(func(int, ServerData) bool { return true })))
+	expectGameTableValue(t, "CopyGameEntry status", QueryEmpty, e.Status)
+
+	e, _ = table.CopyGameEntry("q3a", true)
+	expectGameTableValue(t, "CopyGameEntry with servers", servers, e.Servers.Find(func(int, ServerData) bool { return true }))
+
+	e.Settings.Set("path", "ioquake3")
+	e.Settings.Set("workdir", "/tmp")
+	e.Servers.Insert(makeTestServers("copy", 1))
+	e.Info.Name = "Changed"
+
+	settings, _ := table.Settings("q3a")
+	expectGameTableValue(t, "Settings after copy change", SettingsMap{"path": "quake3"}, settings)
+	tableServers, _ := table.AllServers("q3a")
+	expectGameTableValue(t, "AllServers after copy change", servers, tableServers)
+	tableInfo, _ := table.GameInfo("q3a")
+	expectGameTableValue(t, "GameInfo after copy change", info, tableInfo)
+
+	table.SetSetting("q3a", "nickname", "Player")
+	table.ClearServers("q3a")
+	expectGameTableValue(t, "Copy settings after table change", SettingsMap{"path": "ioquake3", "workdir": "/tmp"}, e.Settings.AllSettings())
+	expectGameTableValue(t, "Copy servers after table change", len(servers)+1, len(e.Servers.Find(func(int, ServerData) bool { return true })))
+}
+
+func checkQueryStatus(t *testing.T, table GameTable) {
+	table.CreateGameEntry("q3a")
+
+	for _, status := range []QueryStatus{QueryReady, QueryError, QueryWorking, QueryEmpty} {
+		expectGameTableError(t, "SetQueryStatus", nil, table.SetQueryStatus("q3a", status))
+		result, err := table.QueryStatus("q3a")
+		expectGameTableError(t, "QueryStatus", nil, err)
+		expectGameTableValue(t, "QueryStatus", status, result)
+	}
+}
+
+func checkTryLockQuery(t *testing.T, table GameTable) {
+	table.CreateGameEntry("q3a")
+
+	locked, err := table.TryLockQuery("q3a")
+	expectGameTableError(t, "TryLockQuery", nil, err)
+	expectGameTableValue(t, "TryLockQuery first", true, locked)
+
+	status, _ := table.QueryStatus("q3a")
+	expectGameTableValue(t, "QueryStatus while locked", QueryWorking, status)
+
+	locked, err = table.TryLockQuery("q3a")
+	expectGameTableError(t, "TryLockQuery", nil, err)
+	expectGameTableValue(t, "TryLockQuery while locked", false, locked)
+
+	for _, status := range []QueryStatus{QueryReady, QueryError, QueryEmpty} {
+		table.SetQueryStatus("q3a", status)
+		locked, _ = table.TryLockQuery("q3a")
+		expectGameTableValue(t, "TryLockQuery after release", true, locked)
+	}
+
+	table.CreateGameEntry("openarena")
+	locked, _ = table.TryLockQuery("openarena")
+	expectGameTableValue(t, "TryLockQuery of another game", true, locked)
+}
+
+func checkGameInfo(t *testing.T, table GameTable) {
+	table.CreateGameEntry("csgo")
+
+	info := GameInfo{Name: "Counter-Strike: Global Offensive", Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML, SteamAppID: "730"}
+	expectGameTableError(t, "SetGameInfo", nil, table.SetGameInfo("csgo", info))
+
+	result, err := table.GameInfo("csgo")
+	expectGameTableError(t, "GameInfo", nil, err)
+	expectGameTableValue(t, "GameInfo", info, result)
+
+	info.Name = "CS:GO"
+	table.SetGameInfo("csgo", info)
+	result, _ = table.GameInfo("csgo")
+	expectGameTableValue(t, "GameInfo replaced", info, result)
+}
+
+func checkSettings(t *testing.T, table GameTable) {
+	table.CreateGameEntry("q3a")
+
+	_, exists, err := table.GetSetting("q3a", "path")
+	expectGameTableError(t, "GetSetting missing key", nil, err)
+	expectGameTableValue(t, "GetSetting missing key exists", false, exists)
+
+	expectGameTableError(t, "SetSetting", nil, table.SetSetting("q3a", "path", "quake3"))
+	table.SetSetting("q3a", "workdir", "")
+	table.SetSetting("q3a", "nickname", "Player")
+	table.SetSetting("q3a", "nickname", "Grunt")
+
+	v, exists, err := table.GetSetting("q3a", "nickname")
+	expectGameTableError(t, "GetSetting", nil, err)
+	expectGameTableValue(t, "GetSetting", "Grunt", v)
+	expectGameTableValue(t, "GetSetting exists", true, exists)
+
+	v, exists, _ = table.GetSetting("q3a", "workdir")
+	expectGameTableValue(t, "GetSetting empty value", "", v)
+	expectGameTableValue(t, "GetSetting empty value exists", true, exists)
+
+	settings, err := table.Settings("q3a")
+	expectGameTableError(t, "Settings", nil, err)
+	expectGameTableValue(t, "Settings", SettingsMap{"path": "quake3", "workdir": "", "nickname": "Grunt"}, settings)
+
+	settings["path"] = "changed"
+	settings, _ = table.Settings("q3a")
+	expectGameTableValue(t, "Settings is a copy", "quake3", settings["path"])
+
+	expectGameTableError(t, "RemoveSetting", nil, table.RemoveSetting("q3a", "workdir"))
+	expectGameTableError(t, "RemoveSetting missing key", nil, table.RemoveSetting("q3a", "workdir"))
+	settings, _ = table.Settings("q3a")
+	expectGameTableValue(t, "Settings after RemoveSetting", SettingsMap{"path": "quake3", "nickname": "Grunt"}, settings)
+
+	table.CreateGameEntry("openarena")
+	table.SetSetting("openarena", "path", "openarena")
+
+	expectGameTableError(t, "ClearSettings", nil, table.ClearSettings("q3a"))
+	settings, _ = table.Settings("q3a")
+	expectGameTableValue(t, "Settings after ClearSettings", SettingsMap{}, settings)
+	settings, _ = table.Settings("openarena")
+	expectGameTableValue(t, "Settings of another game", SettingsMap{"path": "openarena"}, settings)
+}
+
+func checkServers(t *testing.T, table GameTable) {
+	table.CreateGameEntry("q3a")
+	table.CreateGameEntry("openarena")
+
+	servers := makeTestServers("q3a", 10)
+	expectGameTableError(t, "InsertServers", nil, table.InsertServers("q3a", servers[:6]))
+	expectGameTableError(t, "InsertServers more", nil, table.InsertServers("q3a", servers[6:]))
+	table.InsertServers("openarena", makeTestServers("openarena", 2))
+
+	result, err := table.AllServers("q3a")
+	expectGameTableError(t, "AllServers", nil, err)
+	expectGameTableValue(t, "AllServers", servers, result)
+
+	isFull := func(_ int, v ServerData) bool { return v.NumPlayers >= 8 }
+	result, err = table.FindServers("q3a", isFull)
+	expectGameTableError(t, "FindServers", nil, err)
+	expectGameTableValue(t, "FindServers", servers[8:], result)
+
+	deleted, err := table.DeleteServers("q3a", isFull)
+	expectGameTableError(t, "DeleteServers", nil, err)
+	expectGameTableValue(t, "DeleteServers deleted", servers[8:], deleted)
+	result, _ = table.AllServers("q3a")
+	expectGameTableValue(t, "AllServers after DeleteServers", servers[:8], result)
+
+	deleted, _ = table.DeleteServers("q3a", isFull)
+	expectGameTableValue(t, "DeleteServers nothing", 0, len(deleted))
+
+	expectGameTableError(t, "ClearServers", nil, table.ClearServers("q3a"))
+	result, _ = table.AllServers("q3a")
+	expectGameTableValue(t, "AllServers after ClearServers", 0, len(result))
+
+	result, _ = table.AllServers("openarena")
+	expectGameTableValue(t, "AllServers of another game", makeTestServers("openarena", 2), result)
+}
+
+// checkInsertServersUpsert pins down that servers are keyed by host: inserting a known host replaces that server in place, new hosts are appended.
+func checkInsertServersUpsert(t *testing.T, table GameTable) {
+	table.CreateGameEntry("q3a")
+
+	servers := makeTestServers("q3a", 3)
+	table.InsertServers("q3a", servers)
+
+	updated := MakeServerData(ServerData{Host: servers[1].Host, Name: "Renamed", Map: "q3dm17", NumPlayers: 12, MaxPlayers: 16})
+	added := makeTestServers("added", 1)[0]
+	expectGameTableError(t, "InsertServers upsert", nil, table.InsertServers("q3a", []ServerData{updated, added}))
+
+	result, _ := table.AllServers("q3a")
+	expectGameTableValue(t, "AllServers after upsert", []ServerData{servers[0], updated, servers[2], added}, result)
+
+	result, _ = table.FindServers("q3a", func(_ int, v ServerData) bool { return v.NumPlayers >= 12 })
+	expectGameTableValue(t, "FindServers sees the replaced server", []ServerData{updated}, result)
+
+	table.InsertServers("q3a", []ServerData{updated, updated})
+	result, _ = table.AllServers("q3a")
+	expectGameTableValue(t, "AllServers after repeated host", 4, len(result))
+}
+
+func checkReplaceServers(t *testing.T, table GameTable) {
+	table.CreateGameEntry("q3a")
+	table.CreateGameEntry("openarena")
+	servers := makeTestServers("q3a", 4)
+	table.InsertServers("q3a", servers)
+	table.InsertServers("openarena", makeTestServers("openarena", 2))
+
+	updated := MakeServerData(ServerData{Host: servers[2].Host, Name: "Renamed", NumPlayers: 12, MaxPlayers: 16})
+	added := makeTestServers("added", 1)[0]
+	expectGameTableError(t, "ReplaceServers", nil, table.ReplaceServers("q3a", []ServerData{added, updated, servers[0]}))
+
+	result, _ := table.AllServers("q3a")
+	expectGameTableValue(t, "AllServers after ReplaceServers", []ServerData{servers[0], updated, added}, result)
+	result, _ = table.FindServers("q3a", func(_ int, v ServerData) bool { return v.NumPlayers >= 12 })
+	expectGameTableValue(t, "FindServers after ReplaceServers", []ServerData{updated}, result)
+
+	expectGameTableError(t, "ReplaceServers with nothing", nil, table.ReplaceServers("q3a", nil))
+	result, _ = table.AllServers("q3a")
+	expectGameTableValue(t, "AllServers after empty ReplaceServers", 0, len(result))
+
+	result, _ = table.AllServers("openarena")
+	expectGameTableValue(t, "AllServers of another game", makeTestServers("openarena", 2), result)
+	expectGameTableError(t, "ReplaceServers of unknown game", errUnknownGameID, table.ReplaceServers("q2", nil))
+}
+
+func checkFavorites(t *testing.T, table GameTable) {
+	table.CreateGameEntry("q3a")
+	table.CreateGameEntry("openarena")
+
+	favorites, err := table.Favorites("q3a")
+	expectGameTableError(t, "Favorites", nil, err)
+	expectGameTableValue(t, "Favorites empty", 0, len(favorites))
+
+	lan := Favorite{Host: "192.168.1.2:27960", Label: "LAN", Password: "secret", Notes: "Friday games"}
+	public := Favorite{Host: "1.2.3.4:27960"}
+	expectGameTableError(t, "SetFavorite", nil, table.SetFavorite("q3a", public))
+	expectGameTableError(t, "SetFavorite", nil, table.SetFavorite("q3a", lan))
+	expectGameTableError(t, "SetFavorite without host", errInvalidHost, table.SetFavorite("q3a", Favorite{Label: "Nowhere"}))
+
+	favorites, _ = table.Favorites("q3a")
+	expectGameTableValue(t, "Favorites", []Favorite{public, lan}, favorites)
+
+	public.Label = "Public"
+	table.SetFavorite("q3a", public)
+	favorites, _ = table.Favorites("q3a")
+	expectGameTableValue(t, "Favorites after replace", []Favorite{public, lan}, favorites)
+
+	e, _ := table.CopyGameEntry("q3a", false)
+	expectGameTableValue(t, "CopyGameEntry favorites", map[string]Favorite{public.Host: public, lan.Host: lan}, e.Favorites)
+
+	expectGameTableError(t, "RemoveFavorite", nil, table.RemoveFavorite("q3a", public.Host))
+	expectGameTableError(t, "RemoveFavorite missing", errNoSuchFavorite, table.RemoveFavorite("q3a", public.Host))
+	favorites, _ = table.Favorites("q3a")
+	expectGameTableValue(t, "Favorites after remove", []Favorite{lan}, favorites)
+
+	other, _ := table.Favorites("openarena")
+	expectGameTableValue(t, "Favorites of another game", 0, len(other))
+}
+
+func checkServersModDate(t *testing.T, table GameTable) {
+	table.CreateGameEntry("q3a")
+	table.CreateGameEntry("openarena")
+
+	modDt, err := table.ServersModDate("q3a")
+	expectGameTableError(t, "ServersModDate", nil, err)
+
+	table.InsertServers("q3a", makeTestServers("q3a", 3))
+	inserted, _ := table.ServersModDate("q3a")
+	expectGameTableValue(t, "ServersModDate after InsertServers", true, inserted.After(modDt))
+
+	table.DeleteServers("q3a", func(int, ServerData) bool { return false })
+	unchanged, _ := table.ServersModDate("q3a")
+	expectGameTableValue(t, "ServersModDate after deleting nothing", inserted, unchanged)
+
+	time.Sleep(time.Millisecond)
+	table.ClearServers("q3a")
+	cleared, _ := table.ServersModDate("q3a")
+	expectGameTableValue(t, "ServersModDate after ClearServers", true, cleared.After(inserted))
+
+	other, _ := table.ServersModDate("openarena")
+	expectGameTableValue(t, "ServersModDate of another game", true, other.IsZero() || !other.After(modDt))
+}
+
+func checkUnknownGameID(t *testing.T, table GameTable) {
+	table.CreateGameEntry("q3a")
+	const id = GameID("missing")
+
+	_, err := table.QueryStatus(id)
+	expectGameTableError(t, "QueryStatus", errUnknownGameID, err)
+	expectGameTableError(t, "SetQueryStatus", errUnknownGameID, table.SetQueryStatus(id, QueryReady))
+	locked, err := table.TryLockQuery(id)
+	expectGameTableError(t, "TryLockQuery", errUnknownGameID, err)
+	expectGameTableValue(t, "TryLockQuery", false, locked)
+
+	_, err = table.GameInfo(id)
+	expectGameTableError(t, "GameInfo", errUnknownGameID, err)
+	expectGameTableError(t, "SetGameInfo", errUnknownGameID, table.SetGameInfo(id, GameInfo{Name: "Missing"}))
+
+	_, err = table.Settings(id)
+	expectGameTableError(t, "Settings", errUnknownGameID, err)
+	expectGameTableError(t, "SetSetting", errUnknownGameID, table.SetSetting(id, "path", "missing"))
+	_, exists, err := table.GetSetting(id, "path")
+	expectGameTableError(t, "GetSetting", errUnknownGameID, err)
+	expectGameTableValue(t, "GetSetting", false, exists)
+	expectGameTableError(t, "RemoveSetting", errUnknownGameID, table.RemoveSetting(id, "path"))
+	expectGameTableError(t, "ClearSettings", errUnknownGameID, table.ClearSettings(id))
+
+	_, err = table.Favorites(id)
+	expectGameTableError(t, "Favorites", errUnknownGameID, err)
+	expectGameTableError(t, "SetFavorite", errUnknownGameID, table.SetFavorite(id, Favorite{Host: "1.2.3.4:27960"}))
+	expectGameTableError(t, "RemoveFavorite", errUnknownGameID, table.RemoveFavorite(id, "1.2.3.4:27960"))
+
+	_, err = table.FindServers(id, func(int, ServerData) bool { return true })
+	expectGameTableError(t, "FindServers", errUnknownGameID, err)
+	_, err = table.AllServers(id)
+	expectGameTableError(t, "AllServers", errUnknownGameID, err)
+	expectGameTableError(t, "InsertServers", errUnknownGameID, table.InsertServers(id, makeTestServers("missing", 1)))
+	_, err = table.DeleteServers(id, func(int, ServerData) bool { return true })
+	expectGameTableError(t, "DeleteServers", errUnknownGameID, err)
+	expectGameTableError(t, "ClearServers", errUnknownGameID, table.ClearServers(id))
+	expectGameTableError(t, "CloneGameEntry", errUnknownGameID, table.CloneGameEntry(id, "clone", true))
+	expectGameTableError(t, "RenameGameEntry", errUnknownGameID, table.RenameGameEntry(id, "renamed"))
+	_, err = table.ServersModDate(id)
+	expectGameTableError(t, "ServersModDate", errUnknownGameID, err)
+
+	expectGameTableValue(t, "AllGames", []GameID{"q3a"}, table.AllGames())
+}
+
+func checkConcurrentTryLockQuery(t *testing.T, table GameTable) {
+	table.CreateGameEntry("q3a")
+
+	const workers = 32
+	var wg sync.WaitGroup
+	results := make(chan bool, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			locked, _ := table.TryLockQuery("q3a")
+			results <- locked
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var lockCount int
+	for locked := range results {
+		if locked {
+			lockCount++
+		}
+	}
+	expectGameTableValue(t, "TryLockQuery winners", 1, lockCount)
+}
+
+func checkConcurrentAccess(t *testing.T, table GameTable) {
+	games := []GameID{"q3a", "openarena", "xonotic", "warsow"}
+	for _, id := range games {
+		table.CreateGameEntry(id)
+	}
+
+	const rounds = 20
+	var wg sync.WaitGroup
+	for _, id := range games {
+		id := id
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				table.InsertServers(id, makeTestServers(string(id), 5))
+				table.SetQueryStatus(id, QueryReady)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				table.SetSetting(id, "nickname", fmt.Sprint(i))
+				table.SetGameInfo(id, GameInfo{Name: fmt.Sprint(i)})
+				table.GetSetting(id, "nickname")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				table.AllServers(id)
+				table.CopyGameEntry(id, true)
+				table.MatchGameEntries(func(_ GameID, e *GameEntry) bool { return e.Info.Name != "" })
+				table.AllGames()
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			id := GameID(fmt.Sprintf("temporary%d", i))
+			table.CreateGameEntry(id)
+			table.SetSetting(id, "path", "temporary")
+			table.RemoveGameEntry(id)
+		}
+	}()
+	wg.Wait()
+
+	expectGameTableValue(t, "AllGames after concurrent access", sortedGameIDs(games), sortedGameIDs(table.AllGames()))
+	for _, id := range games {
+		servers, _ := table.AllServers(id)
+		expectGameTableValue(t, "AllServers after concurrent access", makeTestServers(string(id), 5), servers)
+
+		v, _, _ := table.GetSetting(id, "nickname")
+		expectGameTableValue(t, "GetSetting after concurrent access", fmt.Sprint(rounds-1), v)
+	}
+}
+
+func TestMemGameTableConformance(t *testing.T) {
+	testGameTableConformance(t, func(*testing.T) GameTable { return MakeMemGameTable() })
+}
+
+func TestFileGameTableConformance(t *testing.T) {
+	testGameTableConformance(t, func(t *testing.T) GameTable {
+		table, err := OpenFileGameTable(filepath.Join(t.TempDir(), "games.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { table.Flush() })
+		return table
+	})
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/skybon/semaphore"
+)
+
+// gameTableSchemaVersion is the current version of the on-disk game table layout. Bump it and register a migration in gameTableMigrations whenever gameTableDump changes incompatibly.
+const gameTableSchemaVersion = 1
+
+// gameTableMigrations upgrade raw dumps from the keyed schema version to the next one.
+var gameTableMigrations = map[int]func(map[string]json.RawMessage) error{}
+
+type gameEntryDump struct {
+	Info      GameInfo     `json:"info"`
+	Settings  SettingsMap  `json:"settings"`
+	Favorites []Favorite   `json:"favorites,omitempty"`
+	Servers   []ServerData `json:"servers"`
+	Status    QueryStatus  `json:"status"`
+}
+
+type gameTableDump struct {
+	SchemaVersion int                      `json:"schema_version"`
+	Games         map[GameID]gameEntryDump `json:"games"`
+}
+
+func dumpGameTable(t GameTable) (gameTableDump, error) {
+	output := gameTableDump{SchemaVersion: gameTableSchemaVersion, Games: map[GameID]gameEntryDump{}}
+
+	for _, id := range t.AllGames() {
+		e, err := t.CopyGameEntry(id, true)
+		if err != nil {
+			return output, err
+		}
+		status, err := t.QueryStatus(id)
+		if err != nil {
+			return output, err
+		}
+		favorites, err := t.Favorites(id)
+		if err != nil {
+			return output, err
+		}
+
+		output.Games[id] = gameEntryDump{
+			Info:      e.Info,
+			Settings:  e.Settings.AllSettings(),
+			Favorites: favorites,
+			Servers:   e.Servers.Find(func(int, ServerData) bool { return true }),
+			Status:    status,
+		}
+	}
+
+	return output, nil
+}
+
+// restoreGameTable fills the table with dumped entries. Queries that were running when the dump was taken are considered lost.
+func restoreGameTable(t GameTable, dump gameTableDump) error {
+	for id, e := range dump.Games {
+		if err := t.CreateGameEntry(id); err != nil {
+			return err
+		}
+		t.SetGameInfo(id, e.Info)
+		for k, v := range e.Settings {
+			t.SetSetting(id, k, v)
+		}
+		for _, f := range e.Favorites {
+			t.SetFavorite(id, f)
+		}
+		if len(e.Servers) > 0 {
+			t.InsertServers(id, e.Servers)
+		}
+
+		status := e.Status
+		if status == QueryWorking {
+			status = QueryEmpty
+		}
+		t.SetQueryStatus(id, status)
+	}
+
+	return nil
+}
+
+func decodeGameTableDump(data []byte) (output gameTableDump, err error) {
+	var raw map[string]json.RawMessage
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return output, err
+	}
+
+	var version int
+	if err = json.Unmarshal(raw["schema_version"], &version); err != nil {
+		return output, errUnknownSchemaVersion
+	}
+	if version > gameTableSchemaVersion {
+		return output, errUnknownSchemaVersion
+	}
+
+	for ; version < gameTableSchemaVersion; version++ {
+		migrate, exists := gameTableMigrations[version]
+		if !exists {
+			return output, errUnknownSchemaVersion
+		}
+		if err = migrate(raw); err != nil {
+			return output, err
+		}
+	}
+
+	raw["schema_version"], _ = json.Marshal(gameTableSchemaVersion)
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return output, err
+	}
+
+	err = json.Unmarshal(data, &output)
+
+	return output, err
+}
+
+// writeFileAtomic replaces the file so that readers and crashes only ever see either the old or the new content.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if d, dirErr := os.Open(dir); dirErr == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// DefaultGameTableSaveDelay is how long a FileGameTable collects changes before it rewrites the file.
+const DefaultGameTableSaveDelay = 2 * time.Second
+
+// FileGameTable is a GameTable that keeps its data in memory and saves it to a single JSON file. Changes go straight to memory, saves are batched and happen at most once per SaveDelay. Call Flush before exiting to write the pending ones.
+type FileGameTable struct {
+	SaveDelay time.Duration
+	// OnSaveError receives the errors of background saves.
+	OnSaveError func(error)
+
+	semaphore semaphore.Semaphore
+	saving    semaphore.Semaphore
+	path      string
+	mem       *MemGameTable
+	dirty     bool
+	timer     *time.Timer
+}
+
+func (t *FileGameTable) safeExec(f func()) { t.semaphore.Exec(f) }
+
+func (t *FileGameTable) save() error {
+	dump, err := dumpGameTable(t.mem)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(dump)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(t.path, data)
+}
+
+func (t *FileGameTable) load() error {
+	data, err := os.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return t.save()
+	}
+	if err != nil {
+		return err
+	}
+
+	dump, err := decodeGameTableDump(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", t.path, err)
+	}
+
+	return restoreGameTable(t.mem, dump)
+}
+
+// modify applies the change to memory and schedules a save. The memory table guards itself, so changes to different games do not wait for each other or for the disk.
+func (t *FileGameTable) modify(f func() error) error {
+	err := f()
+	if err == nil {
+		t.safeExec(func() {
+			t.dirty = true
+			if t.timer == nil {
+				t.timer = time.AfterFunc(t.SaveDelay, t.backgroundSave)
+			}
+		})
+	}
+
+	return err
+}
+
+func (t *FileGameTable) backgroundSave() {
+	if err := t.Flush(); err != nil && t.OnSaveError != nil {
+		t.OnSaveError(err)
+	}
+}
+
+// Flush writes the pending changes to disk right away. A failed save stays pending and is retried with the next change.
+func (t *FileGameTable) Flush() (err error) {
+	t.saving.Exec(func() {
+		var dirty bool
+		t.safeExec(func() {
+			dirty = t.dirty
+			t.dirty = false
+			if t.timer != nil {
+				t.timer.Stop()
+				t.timer = nil
+			}
+		})
+		if !dirty {
+			return
+		}
+
+		if err = t.save(); err != nil {
+			t.safeExec(func() { t.dirty = true })
+		}
+	})
+
+	return err
+}
+
+func (t *FileGameTable) CheckGameEntry(id GameID) bool { return t.mem.CheckGameEntry(id) }
+
+func (t *FileGameTable) AllGames() []GameID { return t.mem.AllGames() }
+
+func (t *FileGameTable) MatchGameEntries(f func(GameID, *GameEntry) bool) []GameID {
+	return t.mem.MatchGameEntries(f)
+}
+
+func (t *FileGameTable) CreateGameEntry(id GameID) error {
+	return t.modify(func() error { return t.mem.CreateGameEntry(id) })
+}
+
+func (t *FileGameTable) RemoveGameEntry(id GameID) error {
+	return t.modify(func() error { return t.mem.RemoveGameEntry(id) })
+}
+
+func (t *FileGameTable) CloneGameEntry(src GameID, dst GameID, servers bool) error {
+	return t.modify(func() error { return t.mem.CloneGameEntry(src, dst, servers) })
+}
+
+func (t *FileGameTable) RenameGameEntry(src GameID, dst GameID) error {
+	return t.modify(func() error { return t.mem.RenameGameEntry(src, dst) })
+}
+
+func (t *FileGameTable) CopyGameEntry(id GameID, servers bool) (*GameEntry, error) {
+	return t.mem.CopyGameEntry(id, servers)
+}
+
+func (t *FileGameTable) QueryStatus(id GameID) (QueryStatus, error) { return t.mem.QueryStatus(id) }
+
+func (t *FileGameTable) SetQueryStatus(id GameID, status QueryStatus) error {
+	return t.modify(func() error { return t.mem.SetQueryStatus(id, status) })
+}
+
+// TryLockQuery is not saved to disk since running queries do not survive restarts anyway.
+func (t *FileGameTable) TryLockQuery(id GameID) (bool, error) { return t.mem.TryLockQuery(id) }
+
+func (t *FileGameTable) GameInfo(id GameID) (GameInfo, error) { return t.mem.GameInfo(id) }
+
+func (t *FileGameTable) SetGameInfo(id GameID, info GameInfo) error {
+	return t.modify(func() error { return t.mem.SetGameInfo(id, info) })
+}
+
+func (t *FileGameTable) Settings(id GameID) (SettingsMap, error) { return t.mem.Settings(id) }
+
+func (t *FileGameTable) SetSetting(id GameID, settingID string, v string) error {
+	return t.modify(func() error { return t.mem.SetSetting(id, settingID, v) })
+}
+
+func (t *FileGameTable) GetSetting(id GameID, settingID string) (string, bool, error) {
+	return t.mem.GetSetting(id, settingID)
+}
+
+func (t *FileGameTable) RemoveSetting(id GameID, settingID string) error {
+	return t.modify(func() error { return t.mem.RemoveSetting(id, settingID) })
+}
+
+func (t *FileGameTable) ClearSettings(id GameID) error {
+	return t.modify(func() error { return t.mem.ClearSettings(id) })
+}
+
+func (t *FileGameTable) Favorites(id GameID) ([]Favorite, error) { return t.mem.Favorites(id) }
+
+func (t *FileGameTable) SetFavorite(id GameID, f Favorite) error {
+	return t.modify(func() error { return t.mem.SetFavorite(id, f) })
+}
+
+func (t *FileGameTable) RemoveFavorite(id GameID, host string) error {
+	return t.modify(func() error { return t.mem.RemoveFavorite(id, host) })
+}
+
+func (t *FileGameTable) FindServers(id GameID, f func(int, ServerData) bool) ([]ServerData, error) {
+	return t.mem.FindServers(id, f)
+}
+
+func (t *FileGameTable) AllServers(id GameID) ([]ServerData, error) { return t.mem.AllServers(id) }
+
+func (t *FileGameTable) InsertServers(id GameID, data []ServerData) error {
+	return t.modify(func() error { return t.mem.InsertServers(id, data) })
+}
+
+func (t *FileGameTable) ReplaceServers(id GameID, data []ServerData) error {
+	return t.modify(func() error { return t.mem.ReplaceServers(id, data) })
+}
+
+func (t *FileGameTable) DeleteServers(id GameID, f func(int, ServerData) bool) (deleted []ServerData, err error) {
+	err = t.modify(func() (deleteErr error) {
+		deleted, deleteErr = t.mem.DeleteServers(id, f)
+		return deleteErr
+	})
+
+	return deleted, err
+}
+
+func (t *FileGameTable) ClearServers(id GameID) error {
+	return t.modify(func() error { return t.mem.ClearServers(id) })
+}
+
+func (t *FileGameTable) ServersModDate(id GameID) (time.Time, error) { return t.mem.ServersModDate(id) }
+
+// OpenFileGameTable loads the game table stored at path, creating the file if it does not exist yet.
+func OpenFileGameTable(path string) (*FileGameTable, error) {
+	t := &FileGameTable{SaveDelay: DefaultGameTableSaveDelay, semaphore: semaphore.MakeSemaphore(1), saving: semaphore.MakeSemaphore(1), path: path, mem: MakeMemGameTable()}
+
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
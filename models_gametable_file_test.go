@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skybon/goutil"
+)
+
+func TestFileGameTableReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.json")
+
+	table, err := OpenFileGameTable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := GameInfo{Name: "Quake III Arena", Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML}
+	servers := []ServerData{MakeServerData(ServerData{Host: "127.0.0.1:27960", Name: "Test", NumPlayers: 3, MaxPlayers: 16})}
+
+	table.CreateGameEntry("q3a")
+	table.SetGameInfo("q3a", info)
+	table.SetSetting("q3a", "path", "quake3")
+	table.SetFavorite("q3a", Favorite{Host: "10.0.0.2:27960", Label: "LAN", Password: "secret"})
+	table.InsertServers("q3a", servers)
+	table.TryLockQuery("q3a")
+	if err := table.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := OpenFileGameTable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result, _ := reloaded.GameInfo("q3a"); !reflect.DeepEqual(info, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, info, result))
+	}
+	if result, _ := reloaded.Settings("q3a"); !reflect.DeepEqual(SettingsMap{"path": "quake3"}, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, SettingsMap{"path": "quake3"}, result))
+	}
+	if result, _ := reloaded.Favorites("q3a"); !reflect.DeepEqual([]Favorite{{Host: "10.0.0.2:27960", Label: "LAN", Password: "secret"}}, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, "LAN favorite", result))
+	}
+	if result, _ := reloaded.AllServers("q3a"); !reflect.DeepEqual(servers, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, servers, result))
+	}
+	if result, _ := reloaded.QueryStatus("q3a"); result != QueryEmpty {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, QueryEmpty, result))
+	}
+
+	tmpFiles, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".*.tmp"))
+	if len(tmpFiles) != 0 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, []string{}, tmpFiles))
+	}
+}
+
+func TestFileGameTableSchemaVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.json")
+	writeTestFile(t, path, `{"schema_version": 999, "games": {}}`)
+
+	if _, err := OpenFileGameTable(path); err == nil {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errUnknownSchemaVersion, err))
+	}
+
+	os.Remove(path)
+	if _, err := OpenFileGameTable(path); err != nil {
+		t.Error(goutil.ErrorOutJSON(err, nil, err))
+	}
+}
+
+func TestFileGameTableBatchesSaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.json")
+
+	table, err := OpenFileGameTable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.SaveDelay = time.Hour
+	t.Cleanup(func() { table.Flush() })
+
+	table.CreateGameEntry("q3a")
+	for i := 0; i < 100; i++ {
+		table.SetSetting("q3a", "nickname", fmt.Sprint(i))
+	}
+
+	reloaded, err := OpenFileGameTable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.CheckGameEntry("q3a") {
+		t.Error("changes were saved before the save delay passed")
+	}
+
+	if err := table.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err = OpenFileGameTable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _, _ := reloaded.GetSetting("q3a", "nickname"); v != "99" {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, "99", v))
+	}
+
+	table.SaveDelay = time.Millisecond
+	table.SetSetting("q3a", "nickname", "late")
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		reloaded, err = OpenFileGameTable(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, _, _ := reloaded.GetSetting("q3a", "nickname"); v == "late" {
+			return
+		}
+	}
+	t.Error("delayed save did not happen")
+}
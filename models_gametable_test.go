@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/skybon/semaphore"
+)
+
+// globalLockGameTable reproduces the former MemGameTable locking: every call goes through one semaphore. It is only used as a baseline in benchmarks.
+type globalLockGameTable struct {
+	semaphore semaphore.Semaphore
+	table     GameTable
+}
+
+func (t *globalLockGameTable) safeExec(f func()) { t.semaphore.Exec(f) }
+
+func (t *globalLockGameTable) CheckGameEntry(id GameID) (v bool) {
+	t.safeExec(func() { v = t.table.CheckGameEntry(id) })
+	return v
+}
+
+func (t *globalLockGameTable) AllGames() (v []GameID) {
+	t.safeExec(func() { v = t.table.AllGames() })
+	return v
+}
+
+func (t *globalLockGameTable) MatchGameEntries(f func(GameID, *GameEntry) bool) (v []GameID) {
+	t.safeExec(func() { v = t.table.MatchGameEntries(f) })
+	return v
+}
+
+func (t *globalLockGameTable) CreateGameEntry(id GameID) (err error) {
+	t.safeExec(func() { err = t.table.CreateGameEntry(id) })
+	return err
+}
+
+func (t *globalLockGameTable) RemoveGameEntry(id GameID) (err error) {
+	t.safeExec(func() { err = t.table.RemoveGameEntry(id) })
+	return err
+}
+
+func (t *globalLockGameTable) CloneGameEntry(src GameID, dst GameID, servers bool) (err error) {
+	t.safeExec(func() { err = t.table.CloneGameEntry(src, dst, servers) })
+	return err
+}
+
+func (t *globalLockGameTable) RenameGameEntry(src GameID, dst GameID) (err error) {
+	t.safeExec(func() { err = t.table.RenameGameEntry(src, dst) })
+	return err
+}
+
+func (t *globalLockGameTable) CopyGameEntry(id GameID, servers bool) (e *GameEntry, err error) {
+	t.safeExec(func() { e, err = t.table.CopyGameEntry(id, servers) })
+	return e, err
+}
+
+func (t *globalLockGameTable) QueryStatus(id GameID) (v QueryStatus, err error) {
+	t.safeExec(func() { v, err = t.table.QueryStatus(id) })
+	return v, err
+}
+
+func (t *globalLockGameTable) SetQueryStatus(id GameID, status QueryStatus) (err error) {
+	t.safeExec(func() { err = t.table.SetQueryStatus(id, status) })
+	return err
+}
+
+func (t *globalLockGameTable) TryLockQuery(id GameID) (v bool, err error) {
+	t.safeExec(func() { v, err = t.table.TryLockQuery(id) })
+	return v, err
+}
+
+func (t *globalLockGameTable) GameInfo(id GameID) (v GameInfo, err error) {
+	t.safeExec(func() { v, err = t.table.GameInfo(id) })
+	return v, err
+}
+
+func (t *globalLockGameTable) SetGameInfo(id GameID, info GameInfo) (err error) {
+	t.safeExec(func() { err = t.table.SetGameInfo(id, info) })
+	return err
+}
+
+func (t *globalLockGameTable) Settings(id GameID) (v SettingsMap, err error) {
+	t.safeExec(func() { v, err = t.table.Settings(id) })
+	return v, err
+}
+
+func (t *globalLockGameTable) SetSetting(id GameID, k string, v string) (err error) {
+	t.safeExec(func() { err = t.table.SetSetting(id, k, v) })
+	return err
+}
+
+func (t *globalLockGameTable) GetSetting(id GameID, k string) (v string, exists bool, err error) {
+	t.safeExec(func() { v, exists, err = t.table.GetSetting(id, k) })
+	return v, exists, err
+}
+
+func (t *globalLockGameTable) RemoveSetting(id GameID, k string) (err error) {
+	t.safeExec(func() { err = t.table.RemoveSetting(id, k) })
+	return err
+}
+
+func (t *globalLockGameTable) ClearSettings(id GameID) (err error) {
+	t.safeExec(func() { err = t.table.ClearSettings(id) })
+	return err
+}
+
+func (t *globalLockGameTable) FindServers(id GameID, f func(int, ServerData) bool) (v []ServerData, err error) {
+	t.safeExec(func() { v, err = t.table.FindServers(id, f) })
+	return v, err
+}
+
+func (t *globalLockGameTable) AllServers(id GameID) (v []ServerData, err error) {
+	t.safeExec(func() { v, err = t.table.AllServers(id) })
+	return v, err
+}
+
+func (t *globalLockGameTable) ReplaceServers(id GameID, data []ServerData) (err error) {
+	t.safeExec(func() { err = t.table.ReplaceServers(id, data) })
+	return err
+}
+
+func (t *globalLockGameTable) InsertServers(id GameID, data []ServerData) (err error) {
+	t.safeExec(func() { err = t.table.InsertServers(id, data) })
+	return err
+}
+
+func (t *globalLockGameTable) DeleteServers(id GameID, f func(int, ServerData) bool) (v []ServerData, err error) {
+	t.safeExec(func() { v, err = t.table.DeleteServers(id, f) })
+	return v, err
+}
+
+func (t *globalLockGameTable) ClearServers(id GameID) (err error) {
+	t.safeExec(func() { err = t.table.ClearServers(id) })
+	return err
+}
+
+func (t *globalLockGameTable) ServersModDate(id GameID) (v time.Time, err error) {
+	t.safeExec(func() { v, err = t.table.ServersModDate(id) })
+	return v, err
+}
+
+func (t *globalLockGameTable) Favorites(id GameID) (v []Favorite, err error) {
+	t.safeExec(func() { v, err = t.table.Favorites(id) })
+	return v, err
+}
+
+func (t *globalLockGameTable) SetFavorite(id GameID, f Favorite) (err error) {
+	t.safeExec(func() { err = t.table.SetFavorite(id, f) })
+	return err
+}
+
+func (t *globalLockGameTable) RemoveFavorite(id GameID, host string) (err error) {
+	t.safeExec(func() { err = t.table.RemoveFavorite(id, host) })
+	return err
+}
+
+func TestGlobalLockGameTableConformance(t *testing.T) {
+	testGameTableConformance(t, func(*testing.T) GameTable {
+		return &globalLockGameTable{semaphore: semaphore.MakeSemaphore(1), table: MakeMemGameTable()}
+	})
+}
+
+// TestMemGameTableRemoveDuringWrite holds an entry lock the way a long InsertServers does and checks that removing that game leaves the other games usable.
+func TestMemGameTableRemoveDuringWrite(t *testing.T) {
+	for _, op := range []struct {
+		Name string
+		F    func(*MemGameTable) error
+	}{
+		{"RemoveGameEntry", func(table *MemGameTable) error { return table.RemoveGameEntry("busy") }},
+		{"RenameGameEntry", func(table *MemGameTable) error { return table.RenameGameEntry("busy", "renamed") }},
+	} {
+		t.Run(op.Name, func(t *testing.T) {
+			table := MakeMemGameTable()
+			table.CreateGameEntry("busy")
+			table.CreateGameEntry("idle")
+
+			g, _ := table.slot("busy")
+			g.lock.RLock()
+
+			done := make(chan error)
+			go func() { done <- op.F(table) }()
+
+			lookup := make(chan error)
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				_, err := table.GameInfo("idle")
+				lookup <- err
+			}()
+			select {
+			case err := <-lookup:
+				expectGameTableError(t, "GameInfo of another game", nil, err)
+			case <-time.After(5 * time.Second):
+				t.Error("lookup of another game waited for the busy entry")
+			}
+
+			g.lock.RUnlock()
+			expectGameTableError(t, op.Name, nil, <-done)
+			if table.CheckGameEntry("busy") {
+				t.Error("busy game is still in the table")
+			}
+		})
+	}
+}
+
+const benchGameCount = 16
+
+func makeBenchGameTable(table GameTable) GameTable {
+	for i := 0; i < benchGameCount; i++ {
+		id := GameID(fmt.Sprintf("game%d", i))
+		table.CreateGameEntry(id)
+		table.SetGameInfo(id, GameInfo{Name: string(id), Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML})
+		table.SetSetting(id, "path", string(id))
+		table.InsertServers(id, makeTestServers(string(id), 500))
+	}
+
+	return table
+}
+
+// benchmarkGameTableMixedLoad runs parallel API-like reads while one in every writeEvery operations refreshes a game with a large server list.
+func benchmarkGameTableMixedLoad(b *testing.B, table GameTable, writeEvery int) {
+	inserted := makeTestServers("refresh", 10000)
+	var counter int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := atomic.AddInt64(&counter, 1)
+			id := GameID(fmt.Sprintf("game%d", n%benchGameCount))
+
+			switch {
+			case n%int64(writeEvery) == 0:
+				table.ClearServers(id)
+				table.InsertServers(id, inserted)
+			case n%3 == 0:
+				table.CopyGameEntry(id, false)
+			case n%3 == 1:
+				table.GameInfo(id)
+				table.Settings(id)
+				table.QueryStatus(id)
+			default:
+				table.FindServers(id, func(_ int, v ServerData) bool { return v.NumPlayers > 8 })
+			}
+		}
+	})
+}
+
+func BenchmarkGameTableMixedLoad(b *testing.B) {
+	for _, writeEvery := range []int{10, 100} {
+		b.Run(fmt.Sprintf("GlobalLock/WriteEvery%d", writeEvery), func(b *testing.B) {
+			benchmarkGameTableMixedLoad(b, makeBenchGameTable(&globalLockGameTable{semaphore: semaphore.MakeSemaphore(1), table: MakeMemGameTable()}), writeEvery)
+		})
+		b.Run(fmt.Sprintf("PerGameLock/WriteEvery%d", writeEvery), func(b *testing.B) {
+			benchmarkGameTableMixedLoad(b, makeBenchGameTable(MakeMemGameTable()), writeEvery)
+		})
+	}
+}
+
+func BenchmarkGameTableReadDuringInsert(b *testing.B) {
+	inserted := makeTestServers("refresh", 10000)
+
+	for _, baseline := range []bool{true, false} {
+		name := "PerGameLock"
+		var table GameTable = MakeMemGameTable()
+		if baseline {
+			name = "GlobalLock"
+			table = &globalLockGameTable{semaphore: semaphore.MakeSemaphore(1), table: MakeMemGameTable()}
+		}
+		makeBenchGameTable(table)
+
+		b.Run(name, func(b *testing.B) {
+			done := make(chan struct{})
+			go func() {
+				for {
+					select {
+					case <-done:
+						return
+					default:
+						table.ClearServers("game0")
+						table.InsertServers("game0", inserted)
+					}
+				}
+			}()
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					table.GameInfo("game1")
+					table.Settings("game1")
+				}
+			})
+			b.StopTimer()
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ServerIndex names a secondary index of IndexedServerCollection.
+type ServerIndex string
+
+const (
+	ServerIndexMap      = ServerIndex("map")
+	ServerIndexGameType = ServerIndex("gametype")
+	ServerIndexPlayers  = ServerIndex("numplayers")
+)
+
+func (i ServerIndex) key(v ServerData) string {
+	switch i {
+	case ServerIndexMap:
+		return v.Map
+	case ServerIndexGameType:
+		return v.GameType
+	case ServerIndexPlayers:
+		return strconv.Itoa(v.NumPlayers)
+	}
+
+	return ""
+}
+
+type indexedServerEntry struct {
+	data  ServerData
+	alive bool
+}
+
+// IndexedServerCollection is a ServerCollection keyed by server address. Inserting a server with a known host replaces it in place, so iteration order stays the order in which hosts were first seen.
+type IndexedServerCollection struct {
+	lock      sync.RWMutex
+	entries   []indexedServerEntry
+	hosts     map[string]int
+	dead      int
+	secondary map[ServerIndex]map[string]map[string]struct{}
+	modDt     time.Time
+}
+
+func (c *IndexedServerCollection) safeExec(f func()) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	f()
+}
+
+func (c *IndexedServerCollection) safeRead(f func()) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	f()
+}
+
+func (c *IndexedServerCollection) bumpModDate() { c.modDt = time.Now() }
+
+func (c *IndexedServerCollection) indexAdd(v ServerData) {
+	for index, values := range c.secondary {
+		k := index.key(v)
+		hosts, exists := values[k]
+		if !exists {
+			hosts = map[string]struct{}{}
+			values[k] = hosts
+		}
+		hosts[v.Host] = struct{}{}
+	}
+}
+
+func (c *IndexedServerCollection) indexRemove(v ServerData) {
+	for index, values := range c.secondary {
+		k := index.key(v)
+		delete(values[k], v.Host)
+		if len(values[k]) == 0 {
+			delete(values, k)
+		}
+	}
+}
+
+func (c *IndexedServerCollection) upsert(v ServerData) {
+	v = MakeServerData(v)
+
+	if pos, exists := c.hosts[v.Host]; exists {
+		c.indexRemove(c.entries[pos].data)
+		c.entries[pos].data = v
+	} else {
+		c.hosts[v.Host] = len(c.entries)
+		c.entries = append(c.entries, indexedServerEntry{data: v, alive: true})
+	}
+	c.indexAdd(v)
+}
+
+// compact drops deleted entries once they take up half of the storage, which keeps deletion amortized O(1).
+func (c *IndexedServerCollection) compact() {
+	if c.dead == 0 || c.dead*2 < len(c.entries) {
+		return
+	}
+
+	newEntries := make([]indexedServerEntry, 0, len(c.entries)-c.dead)
+	for _, e := range c.entries {
+		if e.alive {
+			c.hosts[e.data.Host] = len(newEntries)
+			newEntries = append(newEntries, e)
+		}
+	}
+
+	c.entries = newEntries
+	c.dead = 0
+}
+
+func (c *IndexedServerCollection) deleteHost(host string) (ServerData, bool) {
+	pos, exists := c.hosts[host]
+	if !exists {
+		return ServerData{}, false
+	}
+
+	v := c.entries[pos].data
+	c.indexRemove(v)
+	delete(c.hosts, host)
+	c.entries[pos] = indexedServerEntry{}
+	c.dead++
+
+	return v, true
+}
+
+func (c *IndexedServerCollection) find(f func(int, ServerData) bool) (output []ServerData) {
+	var i int
+	for _, e := range c.entries {
+		if !e.alive {
+			continue
+		}
+		if f(i, e.data) {
+			output = append(output, e.data)
+		}
+		i++
+	}
+
+	return output
+}
+
+func (c *IndexedServerCollection) ModDate() (output time.Time) {
+	c.safeRead(func() { output = c.modDt })
+
+	return output
+}
+
+func (c *IndexedServerCollection) Len() (output int) {
+	c.safeRead(func() { output = len(c.hosts) })
+
+	return output
+}
+
+func (c *IndexedServerCollection) Find(f func(int, ServerData) bool) (output []ServerData) {
+	c.safeRead(func() { output = c.find(f) })
+
+	return output
+}
+
+// Insert upserts every server by its host.
+func (c *IndexedServerCollection) Insert(data []ServerData) error {
+	c.safeExec(func() {
+		for _, v := range data {
+			c.upsert(v)
+		}
+		c.bumpModDate()
+	})
+
+	return nil
+}
+
+// Replace makes data the whole list in one step: hosts missing from it are deleted, the others are upserted in place.
+func (c *IndexedServerCollection) Replace(data []ServerData) error {
+	c.safeExec(func() {
+		listed := make(map[string]bool, len(data))
+		for _, v := range data {
+			listed[v.Host] = true
+		}
+		for host := range c.hosts {
+			if !listed[host] {
+				c.deleteHost(host)
+			}
+		}
+		c.compact()
+		for _, v := range data {
+			c.upsert(v)
+		}
+		c.bumpModDate()
+	})
+
+	return nil
+}
+
+func (c *IndexedServerCollection) Delete(f func(int, ServerData) bool) (output []ServerData) {
+	c.safeExec(func() {
+		for _, v := range c.find(f) {
+			c.deleteHost(v.Host)
+			output = append(output, v)
+		}
+		if len(output) > 0 {
+			c.compact()
+			c.bumpModDate()
+		}
+	})
+
+	return output
+}
+
+// Get looks up a single server by its host.
+func (c *IndexedServerCollection) Get(host string) (output ServerData, exists bool) {
+	c.safeRead(func() {
+		var pos int
+		pos, exists = c.hosts[host]
+		if exists {
+			output = c.entries[pos].data
+		}
+	})
+
+	return output, exists
+}
+
+// Upsert adds the server or replaces the one with the same host.
+func (c *IndexedServerCollection) Upsert(v ServerData) {
+	c.safeExec(func() {
+		c.upsert(v)
+		c.bumpModDate()
+	})
+}
+
+// DeleteHost removes the server with the given host.
+func (c *IndexedServerCollection) DeleteHost(host string) (output ServerData, exists bool) {
+	c.safeExec(func() {
+		output, exists = c.deleteHost(host)
+		if exists {
+			c.compact()
+			c.bumpModDate()
+		}
+	})
+
+	return output, exists
+}
+
+// FindBy returns servers that have the value in the secondary index, in iteration order. It fails with errNoSuchIndex if the collection was created without that index.
+func (c *IndexedServerCollection) FindBy(index ServerIndex, value string) (output []ServerData, err error) {
+	c.safeRead(func() {
+		values, exists := c.secondary[index]
+		if !exists {
+			err = errNoSuchIndex
+			return
+		}
+
+		positions := make([]int, 0, len(values[value]))
+		for host := range values[value] {
+			positions = append(positions, c.hosts[host])
+		}
+		sort.Ints(positions)
+
+		output = make([]ServerData, 0, len(positions))
+		for _, pos := range positions {
+			output = append(output, c.entries[pos].data)
+		}
+	})
+
+	return output, err
+}
+
+// IndexValues returns the number of servers for every value of the secondary index.
+func (c *IndexedServerCollection) IndexValues(index ServerIndex) (output map[string]int, err error) {
+	c.safeRead(func() {
+		values, exists := c.secondary[index]
+		if !exists {
+			err = errNoSuchIndex
+			return
+		}
+
+		output = make(map[string]int, len(values))
+		for k, hosts := range values {
+			output[k] = len(hosts)
+		}
+	})
+
+	return output, err
+}
+
+// MakeIndexedServerCollection creates an empty IndexedServerCollection maintaining the listed secondary indexes.
+func MakeIndexedServerCollection(indexes ...ServerIndex) *IndexedServerCollection {
+	c := &IndexedServerCollection{hosts: map[string]int{}, secondary: map[ServerIndex]map[string]map[string]struct{}{}}
+	for _, index := range indexes {
+		c.secondary[index] = map[string]map[string]struct{}{}
+	}
+
+	return c
+}
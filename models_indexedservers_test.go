@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func hostsOf(servers []ServerData) []string {
+	output := make([]string, 0, len(servers))
+	for _, v := range servers {
+		output = append(output, v.Host)
+	}
+	return output
+}
+
+func TestIndexedServerCollection(t *testing.T) {
+	c := MakeIndexedServerCollection(ServerIndexMap, ServerIndexGameType)
+
+	c.Insert([]ServerData{
+		{Host: "a:1", Map: "q3dm17", GameType: "ffa", NumPlayers: 2},
+		{Host: "b:1", Map: "q3dm6", GameType: "ctf", NumPlayers: 0},
+		{Host: "c:1", Map: "q3dm17", GameType: "ctf", NumPlayers: 5},
+		{Host: "d:1", Map: "q3tourney2", GameType: "1v1", NumPlayers: 1},
+	})
+	modDt := c.ModDate()
+
+	c.Upsert(ServerData{Host: "b:1", Map: "q3dm17", GameType: "ctf", NumPlayers: 8})
+
+	if result := hostsOf(c.Find(func(int, ServerData) bool { return true })); !reflect.DeepEqual([]string{"a:1", "b:1", "c:1", "d:1"}, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, []string{"a:1", "b:1", "c:1", "d:1"}, result))
+	}
+	if v, exists := c.Get("b:1"); !exists || v.NumPlayers != 8 || v.Settings == nil {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, "b:1 with 8 players", v))
+	}
+	if modDt.IsZero() || c.ModDate().Before(modDt) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, modDt, c.ModDate()))
+	}
+
+	result, err := c.FindBy(ServerIndexMap, "q3dm17")
+	if err != nil || !reflect.DeepEqual([]string{"a:1", "b:1", "c:1"}, hostsOf(result)) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, []string{"a:1", "b:1", "c:1"}, hostsOf(result)))
+	}
+	if _, err := c.FindBy(ServerIndexPlayers, "8"); err != errNoSuchIndex {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errNoSuchIndex.Error(), err))
+	}
+
+	if _, exists := c.DeleteHost("a:1"); !exists {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, true, exists))
+	}
+	deleted := c.Delete(func(_ int, v ServerData) bool { return v.GameType == "1v1" })
+	if !reflect.DeepEqual([]string{"d:1"}, hostsOf(deleted)) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, []string{"d:1"}, hostsOf(deleted)))
+	}
+
+	c.Upsert(ServerData{Host: "a:1", Map: "q3dm6", GameType: "ffa"})
+
+	if result := hostsOf(c.Find(func(int, ServerData) bool { return true })); !reflect.DeepEqual([]string{"b:1", "c:1", "a:1"}, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, []string{"b:1", "c:1", "a:1"}, result))
+	}
+
+	gameTypes, _ := c.IndexValues(ServerIndexGameType)
+	if !reflect.DeepEqual(map[string]int{"ctf": 2, "ffa": 1}, gameTypes) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, map[string]int{"ctf": 2, "ffa": 1}, gameTypes))
+	}
+
+	maps, _ := c.IndexValues(ServerIndexMap)
+	if !reflect.DeepEqual(map[string]int{"q3dm17": 2, "q3dm6": 1}, maps) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, map[string]int{"q3dm17": 2, "q3dm6": 1}, maps))
+	}
+
+	if c.Len() != 3 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, 3, c.Len()))
+	}
+}
@@ -1,16 +1,93 @@
 package main
 
+import "time"
+
 type gameEntryPost struct {
-	ID       *GameID           `json:"id"`
-	Proxy    *string           `json:"proxy"`
-	Adapter  *string           `json:"adapter"`
-	Name     *string           `json:"name"`
-	Settings map[string]string `json:"settings"`
+	ID         *GameID                `json:"id"`
+	Proxy      *string                `json:"proxy"`
+	Adapter    *string                `json:"adapter"`
+	Name       *string                `json:"name"`
+	SteamAppID *string                `json:"steam_app_id"`
+	Tags       *[]string              `json:"tags"`
+	Settings   map[string]interface{} `json:"settings"`
 }
 
 type gameEntryEditPost struct {
 	Password  string          `json:"password"`
 	Data      []gameEntryPost `json:"games"`
 	IDs       []string        `json:"ids"`
+	Group     string          `json:"group"`
+	User      string          `json:"user"`
 	NotifyURL string          `json:"notify_url"`
 }
+
+type trashEditPost struct {
+	Password string   `json:"password"`
+	IDs      []string `json:"ids"`
+}
+
+type gameClonePost struct {
+	Password string `json:"password"`
+	Source   GameID `json:"source"`
+	Target   GameID `json:"target"`
+	Servers  bool   `json:"servers"`
+	History  bool   `json:"history"`
+}
+
+type gameMatchPost struct {
+	Password string   `json:"password"`
+	Tags     []string `json:"tags"`
+}
+
+type serverQueryPost struct {
+	Password    string    `json:"password"`
+	GameID      GameID    `json:"game_id"`
+	Host        string    `json:"host"`
+	Since       time.Time `json:"since"`
+	ShowBlocked bool      `json:"show_blocked"`
+}
+
+type buddyEditPost struct {
+	Password string   `json:"password"`
+	Buddies  []Buddy  `json:"buddies"`
+	IDs      []string `json:"ids"`
+	Webhooks []string `json:"webhooks"`
+}
+
+type alertEditPost struct {
+	Password string      `json:"password"`
+	Rules    []AlertRule `json:"rules"`
+	IDs      []string    `json:"ids"`
+}
+
+type favoriteEditPost struct {
+	Password  string     `json:"password"`
+	GameID    GameID     `json:"game_id"`
+	Favorites []Favorite `json:"favorites"`
+	Hosts     []string   `json:"hosts"`
+}
+
+type blockListEditPost struct {
+	Password string     `json:"password"`
+	GameID   GameID     `json:"game_id"`
+	Rules    BlockRules `json:"rules"`
+}
+
+type settingPost struct {
+	Password string      `json:"password"`
+	GameID   GameID      `json:"game_id"`
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+}
+
+type globalSettingsPost struct {
+	Password string                 `json:"password"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+type gameImportPost struct {
+	Password            string `json:"password"`
+	GameLists           string `json:"game_lists"`
+	DefaultGameSettings string `json:"default_game_settings"`
+	DryRun              bool   `json:"dry_run"`
+}
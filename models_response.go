@@ -1,11 +1,17 @@
 package main
 
 type gamesRenderJSON struct {
-	ID       string      `json:"id"`
-	Name     string      `json:"name"`
-	Proxy    ProxyID     `json:"proxy"`
-	Adapter  AdapterID   `json:"adapter"`
-	Settings SettingsMap `json:"settings"`
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Proxy      ProxyID                `json:"proxy"`
+	Adapter    AdapterID              `json:"adapter"`
+	SteamAppID string                 `json:"steam_app_id"`
+	Tags       []string               `json:"tags"`
+	Settings   SettingsMap            `json:"settings"`
+	Values     map[string]interface{} `json:"values"`
+	Schema     []SettingSpec          `json:"schema"`
+	Installed  bool                   `json:"installed"`
+	Install    *InstallInfo           `json:"install"`
 }
 
 type jsonResponse struct {
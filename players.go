@@ -0,0 +1,176 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/skybon/semaphore"
+)
+
+// Player name match modes.
+const (
+	PlayerMatchExact     = "exact"
+	PlayerMatchSubstring = "substring"
+	PlayerMatchPattern   = "pattern"
+)
+
+// colorCodePattern matches Quake-style ^N color codes and Darkplaces ^xRGB ones.
+var colorCodePattern = regexp.MustCompile(`\^(x[0-9a-fA-F]{3}|[0-9a-zA-Z])`)
+
+// StripColorCodes removes color codes from a player or server name.
+func StripColorCodes(s string) string {
+	return colorCodePattern.ReplaceAllString(s, "")
+}
+
+func cleanPlayerName(s string) string {
+	return strings.ToLower(StripColorCodes(s))
+}
+
+// PlayerMatch is a single player found on a server.
+type PlayerMatch struct {
+	GameID     GameID `json:"game_id"`
+	Host       string `json:"host"`
+	ServerName string `json:"server_name"`
+	Map        string `json:"map"`
+	Name       string `json:"name"`
+	Score      string `json:"score"`
+	Ping       string `json:"ping"`
+}
+
+type gamePlayerIndex struct {
+	entries []PlayerMatch
+	raw     map[string][]int
+	lower   map[string][]int
+	clean   map[string][]int
+}
+
+func makeGamePlayerIndex(gameID GameID, servers []ServerData) *gamePlayerIndex {
+	idx := &gamePlayerIndex{raw: map[string][]int{}, lower: map[string][]int{}, clean: map[string][]int{}}
+
+	for _, v := range servers {
+		for _, p := range v.Players {
+			pos := len(idx.entries)
+			idx.entries = append(idx.entries, PlayerMatch{GameID: gameID, Host: v.Host, ServerName: v.Name, Map: v.Map, Name: p.Name, Score: p.Info["score"], Ping: p.Info["ping"]})
+			idx.raw[p.Name] = append(idx.raw[p.Name], pos)
+			idx.lower[strings.ToLower(p.Name)] = append(idx.lower[strings.ToLower(p.Name)], pos)
+			idx.clean[cleanPlayerName(p.Name)] = append(idx.clean[cleanPlayerName(p.Name)], pos)
+		}
+	}
+
+	return idx
+}
+
+// ValidatePlayerQuery checks the match mode and the pattern syntax. Empty match mode defaults to substring.
+func ValidatePlayerQuery(query *PlayerQuery) error {
+	switch {
+	case query.Name == "":
+		return errNoPlayerName
+	case query.Match == "":
+		query.Match = PlayerMatchSubstring
+	case query.Match == PlayerMatchPattern:
+		if _, err := MatchWildcard(query.Name, ""); err != nil {
+			return err
+		}
+	case query.Match != PlayerMatchExact && query.Match != PlayerMatchSubstring:
+		return errInvalidMatchMode
+	}
+
+	return nil
+}
+
+func (idx *gamePlayerIndex) search(query PlayerQuery) []PlayerMatch {
+	var names map[string][]int
+	var needle string
+	switch {
+	case query.IgnoreColors:
+		names, needle = idx.clean, cleanPlayerName(query.Name)
+	case query.Match == PlayerMatchExact:
+		names, needle = idx.raw, query.Name
+	default:
+		names, needle = idx.lower, strings.ToLower(query.Name)
+	}
+
+	var positions []int
+	if query.Match == PlayerMatchExact {
+		positions = names[needle]
+	} else {
+		for name, namePositions := range names {
+			var matched bool
+			if query.Match == PlayerMatchPattern {
+				matched, _ = MatchWildcard(needle, name)
+			} else {
+				matched = strings.Contains(name, needle)
+			}
+			if matched {
+				positions = append(positions, namePositions...)
+			}
+		}
+		sort.Ints(positions)
+	}
+
+	output := make([]PlayerMatch, 0, len(positions))
+	for _, pos := range positions {
+		output = append(output, idx.entries[pos])
+	}
+
+	return output
+}
+
+// PlayerQuery describes a player search. Exact matching compares whole names, substring and pattern matching are case-insensitive. Patterns use shell wildcards such as "*" and "?", which also match "/". IgnoreColors compares names with color codes stripped and case folded.
+type PlayerQuery struct {
+	Name         string   `json:"name"`
+	Match        string   `json:"match"`
+	IgnoreColors bool     `json:"ignore_colors"`
+	GameIDs      []GameID `json:"ids"`
+}
+
+// PlayerIndex maps player names to the servers they play on. Each game's part is rebuilt after its refresh.
+type PlayerIndex struct {
+	data      map[GameID]*gamePlayerIndex
+	semaphore semaphore.Semaphore
+}
+
+func (c *PlayerIndex) Rebuild(gameID GameID, servers []ServerData) {
+	idx := makeGamePlayerIndex(gameID, servers)
+
+	c.semaphore.Exec(func() {
+		c.data[gameID] = idx
+	})
+}
+
+func (c *PlayerIndex) RemoveGame(gameID GameID) {
+	c.semaphore.Exec(func() {
+		delete(c.data, gameID)
+	})
+}
+
+// Search looks for players in the selected games, or in every indexed game if none are selected.
+func (c *PlayerIndex) Search(query PlayerQuery) []PlayerMatch {
+	var indexes []*gamePlayerIndex
+	c.semaphore.Exec(func() {
+		if len(query.GameIDs) == 0 {
+			for _, idx := range c.data {
+				indexes = append(indexes, idx)
+			}
+		} else {
+			for _, id := range query.GameIDs {
+				if idx, exists := c.data[id]; exists {
+					indexes = append(indexes, idx)
+				}
+			}
+		}
+	})
+
+	output := []PlayerMatch{}
+	for _, idx := range indexes {
+		output = append(output, idx.search(query)...)
+	}
+	sort.SliceStable(output, func(i, j int) bool { return output[i].GameID < output[j].GameID })
+
+	return output
+}
+
+func MakePlayerIndex() *PlayerIndex {
+	return &PlayerIndex{data: map[GameID]*gamePlayerIndex{}, semaphore: semaphore.MakeSemaphore(1)}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func makeTestPlayer(name string, score string, ping string) PlayerData {
+	p := NewPlayerData()
+	p.Name = name
+	p.Info["score"] = score
+	p.Info["ping"] = ping
+	return p
+}
+
+func TestStripColorCodes(t *testing.T) {
+	fixtures := map[string]string{
+		"^1Red^7Eye":     "RedEye",
+		"^xF80Orange^7!": "Orange!",
+		"Plain^":         "Plain^",
+		"^^1x":           "^x",
+	}
+
+	for input, fixture := range fixtures {
+		if result := StripColorCodes(input); result != fixture {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+		}
+	}
+}
+
+func TestPlayerIndexSearch(t *testing.T) {
+	c := MakePlayerIndex()
+	c.Rebuild("q3a", []ServerData{
+		{Host: "a:1", Name: "A", Map: "q3dm17", Players: []PlayerData{makeTestPlayer("^1Red^7Eye", "10", "50"), makeTestPlayer("Grunt", "2", "80")}},
+		{Host: "b:1", Name: "B", Map: "q3dm6", Players: []PlayerData{makeTestPlayer("redeye", "0", "0")}},
+	})
+	c.Rebuild("xonotic", []ServerData{
+		{Host: "c:1", Name: "C", Map: "stormkeep", Players: []PlayerData{makeTestPlayer("^xF00RedEye", "5", "30")}},
+	})
+
+	aRedEye := PlayerMatch{GameID: "q3a", Host: "a:1", ServerName: "A", Map: "q3dm17", Name: "^1Red^7Eye", Score: "10", Ping: "50"}
+	bRedEye := PlayerMatch{GameID: "q3a", Host: "b:1", ServerName: "B", Map: "q3dm6", Name: "redeye", Score: "0", Ping: "0"}
+	cRedEye := PlayerMatch{GameID: "xonotic", Host: "c:1", ServerName: "C", Map: "stormkeep", Name: "^xF00RedEye", Score: "5", Ping: "30"}
+
+	fixtures := []struct {
+		Query  PlayerQuery
+		Result []PlayerMatch
+	}{
+		{PlayerQuery{Name: "redeye", Match: PlayerMatchExact}, []PlayerMatch{bRedEye}},
+		{PlayerQuery{Name: "RedEye", Match: PlayerMatchExact, IgnoreColors: true}, []PlayerMatch{aRedEye, bRedEye, cRedEye}},
+		{PlayerQuery{Name: "EYE", Match: PlayerMatchSubstring}, []PlayerMatch{aRedEye, bRedEye, cRedEye}},
+		{PlayerQuery{Name: "dEy", Match: PlayerMatchSubstring}, []PlayerMatch{bRedEye, cRedEye}},
+		{PlayerQuery{Name: "dEy", Match: PlayerMatchSubstring, IgnoreColors: true, GameIDs: []GameID{"q3a"}}, []PlayerMatch{aRedEye, bRedEye}},
+		{PlayerQuery{Name: "nobody", Match: PlayerMatchSubstring}, []PlayerMatch{}},
+	}
+
+	for _, fixture := range fixtures {
+		result := c.Search(fixture.Query)
+		if !reflect.DeepEqual(fixture.Result, result) {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture.Result, result))
+		}
+	}
+
+	c.Rebuild("q3a", nil)
+	if result := c.Search(PlayerQuery{Name: "grunt", Match: PlayerMatchSubstring}); len(result) != 0 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, []PlayerMatch{}, result))
+	}
+}
+
+func TestRefreshDropsUnlistedServers(t *testing.T) {
+	c := StartCore(MakeMemGameTable())
+	listed := []ServerData{
+		{Host: "a:1", Name: "A", Players: []PlayerData{makeTestPlayer("Grunt", "2", "80")}},
+		{Host: "b:1", Name: "B", Players: []PlayerData{makeTestPlayer("RedEye", "10", "50")}},
+	}
+	c.Proxies.Insert("test", func(GameInfo, SettingsMap) ([]string, error) { return []string{}, nil })
+	c.Adapters.Insert("test", func([]string, GameInfo, SettingsMap) ([]ServerData, error) { return listed, nil })
+
+	c.GameTable.CreateGameEntry("q3a")
+	c.GameTable.SetGameInfo("q3a", GameInfo{Proxy: "test", Adapter: "test"})
+	c.SetFavorite("q3a", Favorite{Host: "10.0.0.2:27960", Label: "LAN"})
+
+	refresh := func() {
+		c.statMasterTarget("q3a", func(_ []ServerData, err error) {
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	refresh()
+	listed = listed[:1]
+	refresh()
+
+	var hosts []string
+	servers, _ := c.GameTable.AllServers("q3a")
+	for _, v := range servers {
+		hosts = append(hosts, v.Host)
+	}
+	if fixture := []string{"a:1", "10.0.0.2:27960"}; !reflect.DeepEqual(fixture, hosts) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, hosts))
+	}
+
+	if result := c.Players.Search(PlayerQuery{Name: "redeye", Match: PlayerMatchSubstring}); len(result) != 0 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, []PlayerMatch{}, result))
+	}
+}
@@ -1,17 +1,45 @@
 package main
 
-import "github.com/skybon/semaphore"
+import (
+	"sort"
+
+	"github.com/skybon/semaphore"
+)
 
 type ProxyID string
 
 type ProxyFunc func(GameInfo, SettingsMap) ([]string, error)
 
+// ProxyInfo describes what a proxy consumes and needs. Settings are game setting keys, Options are keys of the catalog's proxy table, passed to the proxy along with the settings, Schemes are the URI schemes accepted in master_uri and Executables must be on $PATH.
+type ProxyInfo struct {
+	ID          ProxyID  `json:"id"`
+	Description string   `json:"description"`
+	Settings    []string `json:"settings"`
+	Options     []string `json:"options"`
+	Schemes     []string `json:"schemes"`
+	Executables []string `json:"executables"`
+}
+
 type ProxyCollection struct {
 	data      map[ProxyID]ProxyFunc
+	info      map[ProxyID]ProxyInfo
 	semaphore semaphore.Semaphore
 }
 
-func (c *ProxyCollection) All() {}
+// All describes every registered proxy, ordered by ID. Proxies without a description only have their ID set.
+func (c *ProxyCollection) All() (output []ProxyInfo) {
+	c.semaphore.Exec(func() {
+		output = make([]ProxyInfo, 0, len(c.data))
+		for k := range c.data {
+			info := c.info[k]
+			info.ID = k
+			output = append(output, info)
+		}
+	})
+	sort.Slice(output, func(i, j int) bool { return output[i].ID < output[j].ID })
+
+	return output
+}
 
 func (c *ProxyCollection) Insert(k ProxyID, v ProxyFunc) {
 	c.semaphore.Exec(func() {
@@ -19,6 +47,13 @@ func (c *ProxyCollection) Insert(k ProxyID, v ProxyFunc) {
 	})
 }
 
+// Describe sets the description of the proxy.
+func (c *ProxyCollection) Describe(k ProxyID, info ProxyInfo) {
+	c.semaphore.Exec(func() {
+		c.info[k] = info
+	})
+}
+
 func (c *ProxyCollection) Retrieve(k ProxyID) (v ProxyFunc, exists bool) {
 	c.semaphore.Exec(func() {
 		v, exists = c.data[k]
@@ -27,6 +62,17 @@ func (c *ProxyCollection) Retrieve(k ProxyID) (v ProxyFunc, exists bool) {
 	return v, exists
 }
 
+// Info returns the description of a registered proxy.
+func (c *ProxyCollection) Info(k ProxyID) (info ProxyInfo, exists bool) {
+	c.semaphore.Exec(func() {
+		_, exists = c.data[k]
+		info = c.info[k]
+	})
+	info.ID = k
+
+	return info, exists
+}
+
 func MakeProxyCollection() *ProxyCollection {
-	return &ProxyCollection{data: make(map[ProxyID]ProxyFunc), semaphore: semaphore.MakeSemaphore(1)}
+	return &ProxyCollection{data: make(map[ProxyID]ProxyFunc), info: make(map[ProxyID]ProxyInfo), semaphore: semaphore.MakeSemaphore(1)}
 }
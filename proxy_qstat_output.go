@@ -1,28 +1,83 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/url"
 	"os/exec"
+	"strings"
+	"time"
 )
 
-func makeQStatArgString(qstatID string, statURLs []url.URL) []string {
-	argString := []string{"-xml", "-utf8", "-R", "-P", "-" + qstatID}
+// makeQStatArgString queries every master with the master type, restricted to gameType if set, and every server with the server type. Empty retries keeps the QStat default.
+func makeQStatArgString(masterType, gameType, serverType, retries string, masters, servers []string) []string {
+	argString := []string{"-xml", "-utf8", "-R", "-P"}
+	if retries != "" {
+		argString = append(argString, "-retry", retries)
+	}
 
-	for _, v := range statURLs {
-		argString = append(argString, v.String())
+	masterArg := "-" + strings.ToLower(masterType)
+	if gameType != "" {
+		masterArg += ",game=" + gameType
+	}
+	for _, v := range masters {
+		argString = append(argString, masterArg, v)
 	}
 
-	argString = append(argString, "-")
+	for _, v := range servers {
+		argString = append(argString, "-"+strings.ToLower(serverType), v)
+	}
 
 	return argString
 }
 
-// GetQStatOutput spuns up QStat and reads XML output.
+// QStatOutputInfo describes the qstat_output proxy.
+var QStatOutputInfo = ProxyInfo{
+	Description: "Queries master servers and favorites with QStat and returns its XML output",
+	Settings:    []string{"master_uri", "query_timeout", "query_retries", FavoritesSetting},
+	Options:     []string{"master_type", "server_type", "server_gametype"},
+	Schemes:     []string{"master"},
+	Executables: []string{"qstat"},
+}
+
+// GetQStatOutput spuns up QStat and reads XML output. Masters come from master_uri, favorites are queried directly so they are listed even when no master knows them. A positive query_timeout kills QStat when it runs longer.
 func GetQStatOutput(info GameInfo, s SettingsMap) ([]string, error) {
-	var statURLs []url.URL
-	var qstatID string
+	var masters []string
+	for _, v := range strings.Fields(s["master_uri"]) {
+		u, err := url.Parse(v)
+		if err != nil || u.Scheme != "master" || u.Host == "" {
+			return nil, fmt.Errorf("%q: %v", v, errUnsupportedMasterURI)
+		}
+		masters = append(masters, u.Host)
+	}
+	servers := strings.Fields(s[FavoritesSetting])
 
-	output, err := exec.Command("qstat", makeQStatArgString(qstatID, statURLs)...).Output()
+	switch {
+	case len(masters) == 0 && len(servers) == 0:
+		return nil, errNoQueryTargets
+	case len(masters) > 0 && s["master_type"] == "":
+		return nil, fmt.Errorf("%v: master_type", errMissingProxyOption)
+	case len(servers) > 0 && s["server_type"] == "":
+		return nil, fmt.Errorf("%v: server_type", errMissingProxyOption)
+	}
+
+	ctx := context.Background()
+	if v := s["query_timeout"]; v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("query_timeout: %v", errSettingNotDuration)
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+
+	output, err := exec.CommandContext(ctx, "qstat", makeQStatArgString(s["master_type"], s["server_gametype"], s["server_type"], s["query_retries"], masters, servers)...).Output()
+	if err != nil {
+		return nil, err
+	}
 
-	return []string{string([]byte(output))}, err
+	return []string{string(output)}, nil
 }
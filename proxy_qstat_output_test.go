@@ -0,0 +1,34 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestMakeQStatArgString(t *testing.T) {
+	fixture := []string{"-xml", "-utf8", "-R", "-P", "-retry", "2", "-stm,game=csgo", "hl2master.steampowered.com:27011", "-a2s", "10.0.0.2:27015"}
+	result := makeQStatArgString("STM", "csgo", "A2S", "2", []string{"hl2master.steampowered.com:27011"}, []string{"10.0.0.2:27015"})
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+}
+
+func TestGetQStatOutputChecksSettings(t *testing.T) {
+	for _, v := range []struct {
+		Settings SettingsMap
+		Err      error
+	}{
+		{SettingsMap{}, errNoQueryTargets},
+		{SettingsMap{"master_uri": "master://a:27950"}, errMissingProxyOption},
+		{SettingsMap{FavoritesSetting: "10.0.0.2:27960", "master_type": "Q3M"}, errMissingProxyOption},
+		{SettingsMap{"master_uri": "http://a/list", "master_type": "Q3M"}, errUnsupportedMasterURI},
+		{SettingsMap{"master_uri": "master://a:27950", "master_type": "Q3M", "query_timeout": "soon"}, errSettingNotDuration},
+	} {
+		if _, err := GetQStatOutput(GameInfo{}, v.Settings); err == nil || !strings.Contains(err.Error(), v.Err.Error()) {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, v.Err.Error(), err))
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import "os/exec"
+
+// DependencyStatus tells whether an executable a proxy needs was found on $PATH.
+type DependencyStatus struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Path      string `json:"path,omitempty"`
+}
+
+// ProxyReport is a proxy description with the state of its dependencies.
+type ProxyReport struct {
+	ProxyInfo
+	Dependencies []DependencyStatus `json:"dependencies"`
+	Available    bool               `json:"available"`
+}
+
+// RegistryReport lists the registered proxies and adapters.
+type RegistryReport struct {
+	Proxies  []ProxyReport `json:"proxies"`
+	Adapters []AdapterInfo `json:"adapters"`
+}
+
+// lookExecutable is exec.LookPath, replaced in tests.
+var lookExecutable = exec.LookPath
+
+func checkDependencies(executables []string) ([]DependencyStatus, bool) {
+	output := make([]DependencyStatus, 0, len(executables))
+	available := true
+	for _, name := range executables {
+		path, err := lookExecutable(name)
+		output = append(output, DependencyStatus{Name: name, Available: err == nil, Path: path})
+		available = available && err == nil
+	}
+
+	return output, available
+}
+
+// Registries describes every registered proxy and adapter and checks whether the proxies' executables are installed.
+func (c *Core) Registries() RegistryReport {
+	output := RegistryReport{Adapters: c.Adapters.All()}
+	for _, info := range c.Proxies.All() {
+		dependencies, available := checkDependencies(info.Executables)
+		output.Proxies = append(output.Proxies, ProxyReport{ProxyInfo: info, Dependencies: dependencies, Available: available})
+	}
+
+	return output
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestRegistries(t *testing.T) {
+	defer func(f func(string) (string, error)) { lookExecutable = f }(lookExecutable)
+	lookExecutable = func(name string) (string, error) {
+		if name == "qstat" {
+			return "/usr/bin/qstat", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	c := StartCore(MakeMemGameTable())
+	c.Proxies.Insert(ProxyNetHTTP, func(GameInfo, SettingsMap) ([]string, error) { return nil, nil })
+	c.Proxies.Describe(ProxyNetHTTP, ProxyInfo{Description: "HTTP", Schemes: []string{"http", "https"}, Executables: []string{"curl"}})
+	c.Adapters.Insert("minetest", func([]string, GameInfo, SettingsMap) ([]ServerData, error) { return nil, nil })
+	c.Proxies.Describe("unregistered", ProxyInfo{Description: "Not inserted"})
+
+	qstat := QStatOutputInfo
+	qstat.ID = ProxyQStatOutput
+	qstatXML := QStatXMLInfo
+	qstatXML.ID = AdapterQStatXML
+
+	fixture := RegistryReport{
+		Proxies: []ProxyReport{
+			{ProxyInfo: ProxyInfo{ID: ProxyNetHTTP, Description: "HTTP", Schemes: []string{"http", "https"}, Executables: []string{"curl"}}, Dependencies: []DependencyStatus{{Name: "curl"}}},
+			{ProxyInfo: qstat, Dependencies: []DependencyStatus{{Name: "qstat", Available: true, Path: "/usr/bin/qstat"}}, Available: true},
+		},
+		Adapters: []AdapterInfo{{ID: "minetest"}, qstatXML},
+	}
+	result := c.Registries()
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+
+	if _, exists := c.Proxies.Info("unregistered"); exists {
+		t.Error("described proxy without a function is reported as registered")
+	}
+	info, exists := c.Adapters.Info(AdapterQStatXML)
+	if !exists || !reflect.DeepEqual(qstatXML, info) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, qstatXML, info))
+	}
+}
+
+func TestCheckGameInfo(t *testing.T) {
+	c := StartCore(MakeMemGameTable())
+	c.Proxies.Insert(ProxyNetHTTP, func(GameInfo, SettingsMap) ([]string, error) { return nil, nil })
+	c.Adapters.Insert("minetest", func([]string, GameInfo, SettingsMap) ([]ServerData, error) { return nil, nil })
+
+	for _, v := range []struct {
+		Info   GameInfo
+		Fields []string
+	}{
+		{GameInfo{}, nil},
+		{GameInfo{Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML}, nil},
+		{GameInfo{Proxy: ProxyNetHTTP, Adapter: "minetest"}, nil},
+		{GameInfo{Proxy: "bogus", Adapter: "minetest"}, []string{"proxy"}},
+		{GameInfo{Proxy: ProxyQStatOutput, Adapter: "bogus"}, []string{"adapter"}},
+		{GameInfo{Proxy: ProxyNetHTTP, Adapter: AdapterQStatXML}, []string{"adapter"}},
+		{GameInfo{Proxy: "bogus", Adapter: "also_bogus"}, []string{"adapter", "proxy"}},
+		{GameInfo{Proxy: ProxyQStatOutput}, []string{"adapter"}},
+		{GameInfo{Adapter: AdapterQStatXML}, []string{"proxy"}},
+	} {
+		err := c.CheckGameInfo(v.Info)
+		var fields []string
+		if err != nil {
+			for k := range err.(FieldErrors) {
+				fields = append(fields, k)
+			}
+			sort.Strings(fields)
+		}
+		if !reflect.DeepEqual(v.Fields, fields) {
+			t.Errorf("%v: %v", v.Info, goutil.ErrorOutJSON(goutil.ErrMismatch, v.Fields, fields))
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SettingType tells how a setting value is validated and presented. Values are stored as strings in the game table.
+type SettingType string
+
+const (
+	SettingString   = SettingType("string")
+	SettingBool     = SettingType("bool")
+	SettingInt      = SettingType("int")
+	SettingDuration = SettingType("duration")
+	SettingPath     = SettingType("path")
+	// SettingURIList is stored as space separated URIs.
+	SettingURIList = SettingType("uri_list")
+)
+
+// SettingSpec describes a setting key.
+type SettingSpec struct {
+	Key         string      `json:"key"`
+	Type        SettingType `json:"type"`
+	Default     interface{} `json:"default"`
+	Description string      `json:"description"`
+}
+
+// settingSpecs are the known setting keys. Catalog defaults override the defaults given here.
+var settingSpecs = map[string]SettingSpec{
+	"path":          {Type: SettingPath, Default: "", Description: "Game executable"},
+	"workdir":       {Type: SettingPath, Default: "", Description: "Directory the game is started in"},
+	"master_uri":    {Type: SettingURIList, Default: []string{}, Description: "Master servers to query"},
+	"nickname":      {Type: SettingString, Default: "", Description: "Player name"},
+	"query_timeout": {Type: SettingDuration, Default: "0s", Description: "How long a server list query may take, 0s for no limit"},
+	"query_retries": {Type: SettingInt, Default: int64(3), Description: "How many times an unresponsive server is queried again"},
+	"steam_launch":  {Type: SettingBool, Default: false, Description: "Start the game through the Steam client"},
+	"steam_path":    {Type: SettingPath, Default: "steam", Description: "Steam client executable"},
+}
+
+// LookupSettingSpec returns the spec of the key. Unknown keys are plain strings.
+func LookupSettingSpec(key string) SettingSpec {
+	spec, exists := settingSpecs[key]
+	if !exists {
+		spec = SettingSpec{Type: SettingString, Default: ""}
+	}
+	spec.Key = key
+
+	return spec
+}
+
+// SettingErrors maps setting keys to what is wrong with their values.
+type SettingErrors map[string]string
+
+func (e SettingErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	messages := make([]string, 0, len(keys))
+	for _, k := range keys {
+		messages = append(messages, fmt.Sprintf("%s: %s", k, e[k]))
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// FieldErrors maps the fields of an entry to what is wrong with them.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string { return SettingErrors(e).Error() }
+
+func settingStrings(v interface{}) ([]string, bool) {
+	switch v := v.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		output := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			output = append(output, s)
+		}
+		return output, true
+	case string:
+		return strings.Fields(v), true
+	}
+
+	return nil, false
+}
+
+// EncodeSettingValue checks a JSON or TOML decoded value against the type and returns its stored form. Strings are accepted for every type.
+func EncodeSettingValue(t SettingType, v interface{}) (string, error) {
+	switch t {
+	case SettingBool:
+		switch v := v.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return "", errSettingNotBool
+			}
+			return strconv.FormatBool(b), nil
+		}
+		return "", errSettingNotBool
+	case SettingInt:
+		switch v := v.(type) {
+		case float64:
+			if v != float64(int64(v)) {
+				return "", errSettingNotInt
+			}
+			return strconv.FormatInt(int64(v), 10), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case int:
+			return strconv.Itoa(v), nil
+		case string:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return "", errSettingNotInt
+			}
+			return strconv.FormatInt(n, 10), nil
+		}
+		return "", errSettingNotInt
+	case SettingDuration:
+		s, ok := v.(string)
+		if !ok {
+			return "", errSettingNotDuration
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return "", errSettingNotDuration
+		}
+		return d.String(), nil
+	case SettingPath:
+		s, ok := v.(string)
+		if !ok || s == "" || strings.ContainsRune(s, 0) {
+			return "", errSettingNotPath
+		}
+		return s, nil
+	case SettingURIList:
+		list, ok := settingStrings(v)
+		if !ok {
+			return "", errSettingNotURIList
+		}
+		for _, item := range list {
+			if u, err := url.Parse(item); err != nil || u.Scheme == "" {
+				return "", fmt.Errorf("%q: %v", item, errSettingNotURIList)
+			}
+		}
+		return strings.Join(list, " "), nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", errSettingNotString
+	}
+
+	return s, nil
+}
+
+// emptyPath tells whether v is an empty path. Catalog defaults and imported files use it for a path that is not set.
+func emptyPath(t SettingType, v interface{}) bool {
+	s, ok := v.(string)
+
+	return t == SettingPath && ok && s == ""
+}
+
+// DecodeSettingValue converts a stored value to its typed form.
+func DecodeSettingValue(t SettingType, s string) (interface{}, error) {
+	switch t {
+	case SettingBool:
+		return strconv.ParseBool(s)
+	case SettingInt:
+		return strconv.ParseInt(s, 10, 64)
+	case SettingDuration:
+		if _, err := time.ParseDuration(s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	case SettingURIList:
+		return strings.Fields(s), nil
+	}
+
+	return s, nil
+}
+
+// typedSettings decodes stored values. Values stored before they were typed are returned as is.
+func typedSettings(settings SettingsMap) map[string]interface{} {
+	output := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		typed, err := DecodeSettingValue(LookupSettingSpec(k).Type, v)
+		if err != nil {
+			typed = v
+		}
+		output[k] = typed
+	}
+
+	return output
+}
+
+// SettingsSchema returns the specs of the game's settings: the catalog list with catalog defaults, followed by other keys the game has set.
+func (c *Core) SettingsSchema(id GameID) []SettingSpec {
+	var output []SettingSpec
+	seen := map[string]bool{}
+
+	if g, exists := c.catalogGame(id); exists {
+		for _, k := range g.Settings {
+			spec := LookupSettingSpec(k)
+			if v, exists := g.Defaults[k]; exists {
+				if s, err := EncodeSettingValue(spec.Type, v); err == nil {
+					spec.Default, _ = DecodeSettingValue(spec.Type, s)
+				}
+			}
+			output = append(output, spec)
+			seen[k] = true
+		}
+	}
+
+	settings, _ := c.GameTable.Settings(id)
+	var extra []string
+	for k := range settings {
+		if !seen[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	for _, k := range extra {
+		output = append(output, LookupSettingSpec(k))
+	}
+
+	return output
+}
+
+// checkSetting validates the key against the catalog and the value against the key's type, returning the value to store.
+func (c *Core) checkSetting(id GameID, key string, v interface{}) (string, error) {
+	if err := c.catalog().CheckSetting(c.catalogID(id), key); err != nil {
+		return "", errUnknownSetting
+	}
+
+	return EncodeSettingValue(LookupSettingSpec(key).Type, v)
+}
+
+// SetSetting changes a single setting of the game after checking it against the catalog and the schema.
+func (c *Core) SetSetting(id GameID, key string, v interface{}) error {
+	if key == "" {
+		return errNoSettingKey
+	}
+	if !c.GameTable.CheckGameEntry(id) {
+		return errUnknownGameID
+	}
+
+	s, err := c.checkSetting(id, key, v)
+	if err != nil {
+		return SettingErrors{key: err.Error()}
+	}
+
+	return c.GameTable.SetSetting(id, key, s)
+}
+
+// RemoveSetting removes a single setting of the game.
+func (c *Core) RemoveSetting(id GameID, key string) error {
+	_, exists, err := c.GameTable.GetSetting(id, key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errNoSuchSetting
+	}
+
+	return c.GameTable.RemoveSetting(id, key)
+}
+
+// CheckSettings validates a settings patch and returns the values to store. Null values and empty paths mark keys to remove.
+func (c *Core) CheckSettings(id GameID, patch map[string]interface{}) (map[string]*string, error) {
+	output := make(map[string]*string, len(patch))
+	errs := SettingErrors{}
+	for k, v := range patch {
+		if k == "" {
+			return nil, errNoSettingKey
+		}
+		if v == nil || emptyPath(LookupSettingSpec(k).Type, v) {
+			output[k] = nil
+			continue
+		}
+		s, err := c.checkSetting(id, k, v)
+		if err != nil {
+			errs[k] = err.Error()
+			continue
+		}
+		output[k] = &s
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return output, nil
+}
+
+// MergeSettings applies a settings patch: keys with values are set, null keys are removed and absent keys are kept. Nothing is changed if any key is invalid.
+func (c *Core) MergeSettings(id GameID, patch map[string]interface{}) error {
+	values, err := c.CheckSettings(id, patch)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range values {
+		if v == nil {
+			err = c.GameTable.RemoveSetting(id, k)
+		} else {
+			err = c.GameTable.SetSetting(id, k, *v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
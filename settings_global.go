@@ -0,0 +1,125 @@
+package main
+
+import "github.com/skybon/semaphore"
+
+// Where an effective setting value comes from.
+const (
+	SettingSourceGame    = "game"
+	SettingSourceGlobal  = "global"
+	SettingSourceDefault = "default"
+)
+
+// GlobalSettings are setting values inherited by every game that does not set them itself.
+type GlobalSettings struct {
+	path      string
+	data      SettingsMap
+	semaphore semaphore.Semaphore
+}
+
+func (s *GlobalSettings) All() (output SettingsMap) {
+	s.semaphore.Exec(func() {
+		output = make(SettingsMap, len(s.data))
+		for k, v := range s.data {
+			output[k] = v
+		}
+	})
+
+	return output
+}
+
+// Merge sets keys with values and removes null keys. Values must already be in their stored form.
+func (s *GlobalSettings) Merge(patch map[string]*string) (err error) {
+	s.semaphore.Exec(func() {
+		for k, v := range patch {
+			if v == nil {
+				delete(s.data, k)
+			} else {
+				s.data[k] = *v
+			}
+		}
+		err = saveJSONFile(s.path, s.data)
+	})
+
+	return err
+}
+
+// LoadGlobalSettings reads global settings from path. Empty path keeps them in memory only.
+func LoadGlobalSettings(path string) (*GlobalSettings, error) {
+	s := &GlobalSettings{path: path, data: SettingsMap{}, semaphore: semaphore.MakeSemaphore(1)}
+	if err := loadJSONFile(path, &s.data); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// MakeGlobalSettings creates empty in-memory global settings.
+func MakeGlobalSettings() *GlobalSettings {
+	s, _ := LoadGlobalSettings("")
+	return s
+}
+
+// MergeGlobalSettings validates the patch against the schema and applies it. Nothing is changed if any value is invalid.
+func (c *Core) MergeGlobalSettings(patch map[string]interface{}) error {
+	values := make(map[string]*string, len(patch))
+	errs := SettingErrors{}
+	for k, v := range patch {
+		if k == "" {
+			return errNoSettingKey
+		}
+		if v == nil {
+			values[k] = nil
+			continue
+		}
+		s, err := EncodeSettingValue(LookupSettingSpec(k).Type, v)
+		if err != nil {
+			errs[k] = err.Error()
+			continue
+		}
+		values[k] = &s
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return c.Globals.Merge(values)
+}
+
+// ResolveSettings returns the game's effective settings and where each value comes from: the game itself, then global settings, then catalog defaults.
+// Games in the catalog only inherit global values of keys they list.
+func (c *Core) ResolveSettings(id GameID) (SettingsMap, map[string]string, error) {
+	own, err := c.GameTable.Settings(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	output := SettingsMap{}
+	sources := map[string]string{}
+
+	g, inCatalog := c.catalogGame(id)
+	for k, v := range g.Defaults {
+		if s, err := EncodeSettingValue(LookupSettingSpec(k).Type, v); err == nil {
+			output[k] = s
+			sources[k] = SettingSourceDefault
+		}
+	}
+	for k, v := range c.Globals.All() {
+		if !inCatalog || g.AllowsSetting(k) {
+			output[k] = v
+			sources[k] = SettingSourceGlobal
+		}
+	}
+	for k, v := range own {
+		output[k] = v
+		sources[k] = SettingSourceGame
+	}
+
+	return output, sources, nil
+}
+
+// ResolvedSettings returns the game's effective settings.
+func (c *Core) ResolvedSettings(id GameID) (SettingsMap, error) {
+	output, _, err := c.ResolveSettings(id)
+
+	return output, err
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestCoreResolveSettings(t *testing.T) {
+	c := makeTestSettingsCore(t)
+	c.GameTable.CreateGameEntry("custom")
+	c.GameTable.SetSetting("minetest", "path", "/opt/minetest/bin/minetest")
+
+	if err := c.MergeGlobalSettings(map[string]interface{}{"nickname": "Player", "steam_path": "/usr/bin/steam", "steam_launch": "sometimes"}); err == nil {
+		t.Error("invalid global value must be rejected")
+	}
+	if err := c.MergeGlobalSettings(map[string]interface{}{"nickname": "Player", "steam_path": "/usr/bin/steam"}); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, sources, err := c.ResolveSettings("minetest")
+	expectGameTableError(t, "ResolveSettings", nil, err)
+	expectGameTableValue(t, "ResolveSettings values", SettingsMap{"path": "/opt/minetest/bin/minetest", "master_uri": "http://servers.minetest.net", "nickname": "Player"}, settings)
+	expectGameTableValue(t, "ResolveSettings sources", map[string]string{"path": SettingSourceGame, "master_uri": SettingSourceDefault, "nickname": SettingSourceGlobal}, sources)
+
+	settings, _ = c.ResolvedSettings("custom")
+	expectGameTableValue(t, "ResolvedSettings outside catalog", SettingsMap{"nickname": "Player", "steam_path": "/usr/bin/steam"}, settings)
+
+	c.MergeGlobalSettings(map[string]interface{}{"nickname": nil})
+	settings, _ = c.ResolvedSettings("custom")
+	expectGameTableValue(t, "ResolvedSettings after global removal", SettingsMap{"steam_path": "/usr/bin/steam"}, settings)
+
+	_, _, err = c.ResolveSettings("missing")
+	expectGameTableError(t, "ResolveSettings missing", errUnknownGameID, err)
+}
+
+func TestGlobalSettingsPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "global_settings.json")
+
+	s, err := LoadGlobalSettings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nickname := "Player"
+	s.Merge(map[string]*string{"nickname": &nickname})
+
+	restored, err := LoadGlobalSettings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.All(), restored.All()) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, s.All(), restored.All()))
+	}
+}
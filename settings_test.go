@@ -0,0 +1,128 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skybon/goutil"
+)
+
+func TestEncodeSettingValue(t *testing.T) {
+	fixtures := []struct {
+		Type   SettingType
+		Input  interface{}
+		Result string
+		Err    bool
+	}{
+		{SettingString, "Player", "Player", false},
+		{SettingString, 1.0, "", true},
+		{SettingBool, true, "true", false},
+		{SettingBool, "0", "false", false},
+		{SettingBool, "maybe", "", true},
+		{SettingInt, 27960.0, "27960", false},
+		{SettingInt, 1.5, "", true},
+		{SettingInt, "-3", "-3", false},
+		{SettingDuration, "90s", "1m30s", false},
+		{SettingDuration, 5.0, "", true},
+		{SettingPath, "~/games/quake3", "~/games/quake3", false},
+		{SettingPath, "", "", true},
+		{SettingPath, "quake3\x00", "", true},
+		{SettingURIList, []interface{}{"master://a:27011", "http://b/list"}, "master://a:27011 http://b/list", false},
+		{SettingURIList, "master://a:27011 master://b:27011", "master://a:27011 master://b:27011", false},
+		{SettingURIList, []interface{}{"not a uri"}, "", true},
+		{SettingURIList, []interface{}{1.0}, "", true},
+	}
+
+	for _, fixture := range fixtures {
+		result, err := EncodeSettingValue(fixture.Type, fixture.Input)
+		if (err != nil) != fixture.Err || result != fixture.Result {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, []interface{}{result, err}))
+		}
+	}
+
+	if v, _ := DecodeSettingValue(SettingURIList, "master://a:27011 master://b:27011"); !reflect.DeepEqual([]string{"master://a:27011", "master://b:27011"}, v) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, "two URIs", v))
+	}
+	if v, _ := DecodeSettingValue(SettingBool, "true"); v != true {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, true, v))
+	}
+	if _, err := DecodeSettingValue(SettingDuration, time.Minute.String()); err != nil {
+		t.Error(err)
+	}
+}
+
+func makeTestSettingsCore(t *testing.T) *Core {
+	c := StartCore(MakeMemGameTable())
+	catalog, err := ParseCatalog(
+		[]byte("[minetest]\nsettings = [\"path\", \"master_uri\", \"nickname\", \"steam_launch\"]\n"),
+		[]byte("[minetest]\npath = \"minetest\"\nmaster_uri = [\"http://servers.minetest.net\"]\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetCatalog(catalog)
+	c.GameTable.CreateGameEntry("minetest")
+
+	return c
+}
+
+func TestCoreMergeSettings(t *testing.T) {
+	c := makeTestSettingsCore(t)
+	c.GameTable.SetSetting("minetest", "path", "minetest")
+	c.GameTable.SetSetting("minetest", "master_uri", "http://servers.minetest.net")
+
+	if err := c.MergeSettings("minetest", map[string]interface{}{"nickname": "Player", "path": ""}); err != nil {
+		t.Fatal(err)
+	}
+	expectGameTableSettings(t, c.GameTable, "minetest", SettingsMap{"master_uri": "http://servers.minetest.net", "nickname": "Player"})
+
+	err := c.MergeSettings("minetest", map[string]interface{}{"nickname": nil, "bogus": "1", "steam_launch": "maybe"})
+	errFixture := SettingErrors{"bogus": errUnknownSetting.Error(), "steam_launch": errSettingNotBool.Error()}
+	if !reflect.DeepEqual(errFixture, err) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errFixture, err))
+	}
+	expectGameTableSettings(t, c.GameTable, "minetest", SettingsMap{"master_uri": "http://servers.minetest.net", "nickname": "Player"})
+
+	if err := c.SetSetting("minetest", "bogus", "1"); err == nil {
+		t.Error("bogus setting must be rejected")
+	}
+	expectGameTableError(t, "SetSetting missing game", errUnknownGameID, c.SetSetting("missing", "path", "x"))
+	expectGameTableError(t, "SetSetting", nil, c.SetSetting("minetest", "path", "/opt/minetest"))
+	expectGameTableError(t, "SetSetting bool", nil, c.SetSetting("minetest", "steam_launch", true))
+	if err := c.SetSetting("minetest", "path", ""); err == nil {
+		t.Error("empty path must be rejected")
+	}
+	expectGameTableError(t, "RemoveSetting", nil, c.RemoveSetting("minetest", "master_uri"))
+	expectGameTableError(t, "RemoveSetting twice", errNoSuchSetting, c.RemoveSetting("minetest", "master_uri"))
+	expectGameTableSettings(t, c.GameTable, "minetest", SettingsMap{"path": "/opt/minetest", "nickname": "Player", "steam_launch": "true"})
+}
+
+func TestCoreSettingsSchema(t *testing.T) {
+	c := makeTestSettingsCore(t)
+	c.GameTable.SetSetting("minetest", "legacy", "value")
+	c.GameTable.SetSetting("minetest", "master_uri", "http://a/ http://b/")
+
+	fixture := []SettingSpec{
+		{Key: "path", Type: SettingPath, Default: "minetest", Description: settingSpecs["path"].Description},
+		{Key: "master_uri", Type: SettingURIList, Default: []string{"http://servers.minetest.net"}, Description: settingSpecs["master_uri"].Description},
+		{Key: "nickname", Type: SettingString, Default: "", Description: settingSpecs["nickname"].Description},
+		{Key: "steam_launch", Type: SettingBool, Default: false, Description: settingSpecs["steam_launch"].Description},
+		{Key: "legacy", Type: SettingString, Default: ""},
+	}
+	if result := c.SettingsSchema("minetest"); !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+
+	settings, _ := c.GameTable.Settings("minetest")
+	valuesFixture := map[string]interface{}{"legacy": "value", "master_uri": []string{"http://a/", "http://b/"}}
+	if result := typedSettings(settings); !reflect.DeepEqual(valuesFixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, valuesFixture, result))
+	}
+}
+
+func expectGameTableSettings(t *testing.T, table GameTable, id GameID, fixture SettingsMap) {
+	t.Helper()
+	result, _ := table.Settings(id)
+	expectGameTableValue(t, "Settings", fixture, result)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SaveSnapshot dumps the whole game table to a versioned JSON file.
+func SaveSnapshot(t GameTable, path string) error {
+	dump, err := dumpGameTable(t)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(dump)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+// LoadSnapshot fills the game table from a snapshot file. Games that already exist in the table are kept as they are.
+func LoadSnapshot(t GameTable, path string) (restored []GameID, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dump, err := decodeGameTableDump(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	for id := range dump.Games {
+		if t.CheckGameEntry(id) {
+			delete(dump.Games, id)
+		} else {
+			restored = append(restored, id)
+		}
+	}
+
+	return restored, restoreGameTable(t, dump)
+}
+
+// snapshotWriter periodically saves the game table until stopped.
+type snapshotWriter struct {
+	path     string
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func (w *snapshotWriter) run(t GameTable, onSave func(error)) {
+	defer close(w.done)
+
+	if w.interval <= 0 {
+		<-w.stop
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			onSave(SaveSnapshot(t, w.path))
+		}
+	}
+}
+
+// Stop ends periodic saving and waits for a running save to finish.
+func (w *snapshotWriter) Stop() {
+	close(w.stop)
+	<-w.done
+}
+
+func startSnapshotWriter(t GameTable, path string, interval time.Duration, onSave func(error)) *snapshotWriter {
+	w := &snapshotWriter{path: path, interval: interval, stop: make(chan struct{}), done: make(chan struct{})}
+	go w.run(t, onSave)
+
+	return w
+}
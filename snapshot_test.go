@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skybon/goutil"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	source := MakeMemGameTable()
+	servers := makeTestServers("q3a", 3)
+	source.CreateGameEntry("q3a")
+	source.SetGameInfo("q3a", GameInfo{Name: "Quake III Arena", Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML})
+	source.SetSetting("q3a", "path", "quake3")
+	source.InsertServers("q3a", servers)
+	source.SetQueryStatus("q3a", QueryReady)
+	source.CreateGameEntry("openarena")
+	source.SetSetting("openarena", "path", "openarena")
+
+	if err := SaveSnapshot(source, path); err != nil {
+		t.Fatal(err)
+	}
+
+	target := MakeMemGameTable()
+	target.CreateGameEntry("openarena")
+
+	restored, err := LoadSnapshot(target, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]GameID{"q3a"}, restored) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, []GameID{"q3a"}, restored))
+	}
+
+	if result, _ := target.AllServers("q3a"); !reflect.DeepEqual(servers, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, servers, result))
+	}
+	if result, _ := target.QueryStatus("q3a"); result != QueryReady {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, QueryReady, result))
+	}
+	if result, _ := target.Settings("openarena"); len(result) != 0 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, SettingsMap{}, result))
+	}
+}
+
+func TestSnapshotWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	table := MakeMemGameTable()
+	table.CreateGameEntry("q3a")
+
+	saved := make(chan error, 16)
+	w := startSnapshotWriter(table, path, 10*time.Millisecond, func(err error) { saved <- err })
+
+	select {
+	case err := <-saved:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Snapshot was not saved")
+	}
+	w.Stop()
+
+	restored, err := LoadSnapshot(MakeMemGameTable(), path)
+	if err != nil || !reflect.DeepEqual([]GameID{"q3a"}, restored) {
+		t.Error(goutil.ErrorOutJSON(err, []GameID{"q3a"}, restored))
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/skybon/semaphore"
+)
+
+// statsTopCount limits the length of top maps and busiest servers lists.
+const statsTopCount = 10
+
+type MapStats struct {
+	Map     string `json:"map"`
+	Players int    `json:"players"`
+	Servers int    `json:"servers"`
+}
+
+type ServerSummary struct {
+	Host       string `json:"host"`
+	Name       string `json:"name"`
+	Map        string `json:"map"`
+	NumPlayers int    `json:"numplayers"`
+	MaxPlayers int    `json:"maxplayers"`
+}
+
+// GameStats contains aggregate numbers of a single game's server list.
+type GameStats struct {
+	ModDate     time.Time       `json:"mod_date"`
+	Servers     int             `json:"servers"`
+	ServersUp   int             `json:"servers_up"`
+	ServersDown int             `json:"servers_down"`
+	Players     int             `json:"players"`
+	Slots       int             `json:"slots"`
+	FullShare   float64         `json:"full_share"`
+	EmptyShare  float64         `json:"empty_share"`
+	TopMaps     []MapStats      `json:"top_maps"`
+	GameTypes   map[string]int  `json:"gametypes"`
+	Busiest     []ServerSummary `json:"busiest"`
+}
+
+type GamePlayers struct {
+	ID      GameID `json:"id"`
+	Players int    `json:"players"`
+}
+
+// StatsSummary aggregates GameStats across games.
+type StatsSummary struct {
+	Games       int           `json:"games"`
+	Servers     int           `json:"servers"`
+	ServersUp   int           `json:"servers_up"`
+	ServersDown int           `json:"servers_down"`
+	Players     int           `json:"players"`
+	Slots       int           `json:"slots"`
+	BusiestGame []GamePlayers `json:"busiest_games"`
+}
+
+// ComputeGameStats aggregates a server list. Only servers that are up count towards players, maps, game types and fill shares.
+func ComputeGameStats(servers []ServerData) GameStats {
+	output := GameStats{Servers: len(servers), GameTypes: map[string]int{}, TopMaps: []MapStats{}, Busiest: []ServerSummary{}}
+
+	maps := map[string]*MapStats{}
+	var full, empty int
+	for _, v := range servers {
+		if !isServerUp(v) {
+			output.ServersDown++
+			continue
+		}
+
+		output.ServersUp++
+		output.Players += v.NumPlayers
+		output.Slots += v.MaxPlayers
+		switch {
+		case v.NumPlayers == 0:
+			empty++
+		case v.MaxPlayers > 0 && v.NumPlayers >= v.MaxPlayers:
+			full++
+		}
+
+		if v.GameType != "" {
+			output.GameTypes[v.GameType]++
+		}
+
+		if v.Map != "" {
+			m, exists := maps[v.Map]
+			if !exists {
+				m = &MapStats{Map: v.Map}
+				maps[v.Map] = m
+			}
+			m.Players += v.NumPlayers
+			m.Servers++
+		}
+
+		output.Busiest = append(output.Busiest, ServerSummary{Host: v.Host, Name: v.Name, Map: v.Map, NumPlayers: v.NumPlayers, MaxPlayers: v.MaxPlayers})
+	}
+
+	if output.ServersUp > 0 {
+		output.FullShare = float64(full) / float64(output.ServersUp)
+		output.EmptyShare = float64(empty) / float64(output.ServersUp)
+	}
+
+	for _, m := range maps {
+		output.TopMaps = append(output.TopMaps, *m)
+	}
+	sort.Slice(output.TopMaps, func(i, j int) bool {
+		a, b := output.TopMaps[i], output.TopMaps[j]
+		if a.Players != b.Players {
+			return a.Players > b.Players
+		}
+		if a.Servers != b.Servers {
+			return a.Servers > b.Servers
+		}
+		return a.Map < b.Map
+	})
+	if len(output.TopMaps) > statsTopCount {
+		output.TopMaps = output.TopMaps[:statsTopCount]
+	}
+
+	sort.SliceStable(output.Busiest, func(i, j int) bool { return output.Busiest[i].NumPlayers > output.Busiest[j].NumPlayers })
+	if len(output.Busiest) > statsTopCount {
+		output.Busiest = output.Busiest[:statsTopCount]
+	}
+
+	return output
+}
+
+// SummarizeStats builds the cross-game summary.
+func SummarizeStats(games map[GameID]GameStats) StatsSummary {
+	output := StatsSummary{Games: len(games), BusiestGame: []GamePlayers{}}
+
+	for id, v := range games {
+		output.Servers += v.Servers
+		output.ServersUp += v.ServersUp
+		output.ServersDown += v.ServersDown
+		output.Players += v.Players
+		output.Slots += v.Slots
+		output.BusiestGame = append(output.BusiestGame, GamePlayers{ID: id, Players: v.Players})
+	}
+
+	sort.Slice(output.BusiestGame, func(i, j int) bool {
+		a, b := output.BusiestGame[i], output.BusiestGame[j]
+		if a.Players != b.Players {
+			return a.Players > b.Players
+		}
+		return a.ID < b.ID
+	})
+
+	return output
+}
+
+// StatsCache keeps computed GameStats until the game's server list changes.
+type StatsCache struct {
+	data      map[GameID]GameStats
+	semaphore semaphore.Semaphore
+}
+
+func (c *StatsCache) Retrieve(t GameTable, id GameID) (GameStats, error) {
+	modDt, err := t.ServersModDate(id)
+	if err != nil {
+		return GameStats{}, err
+	}
+
+	var v GameStats
+	var cached bool
+	c.semaphore.Exec(func() {
+		v, cached = c.data[id]
+	})
+	if cached && v.ModDate.Equal(modDt) {
+		return v, nil
+	}
+
+	servers, err := t.AllServers(id)
+	if err != nil {
+		return GameStats{}, err
+	}
+
+	v = ComputeGameStats(servers)
+	v.ModDate = modDt
+	c.semaphore.Exec(func() {
+		c.data[id] = v
+	})
+
+	return v, nil
+}
+
+func (c *StatsCache) Remove(id GameID) {
+	c.semaphore.Exec(func() {
+		delete(c.data, id)
+	})
+}
+
+func MakeStatsCache() *StatsCache {
+	return &StatsCache{data: map[GameID]GameStats{}, semaphore: semaphore.MakeSemaphore(1)}
+}
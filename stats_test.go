@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestComputeGameStats(t *testing.T) {
+	servers := []ServerData{
+		{Host: "a:1", Name: "A", Status: "UP", Map: "q3dm17", GameType: "ffa", NumPlayers: 16, MaxPlayers: 16},
+		{Host: "b:1", Name: "B", Status: "UP", Map: "q3dm6", GameType: "ctf", NumPlayers: 0, MaxPlayers: 12},
+		{Host: "c:1", Name: "C", Status: "UP", Map: "q3dm17", GameType: "ffa", NumPlayers: 3, MaxPlayers: 8},
+		{Host: "d:1", Name: "D", Status: "UP", Map: "q3tourney2", GameType: "1v1", NumPlayers: 0, MaxPlayers: 2},
+		{Host: "e:1", Name: "E", Status: "TIMEOUT", Map: "q3dm6", NumPlayers: 10, MaxPlayers: 16},
+	}
+
+	fixture := GameStats{
+		Servers:     5,
+		ServersUp:   4,
+		ServersDown: 1,
+		Players:     19,
+		Slots:       38,
+		FullShare:   0.25,
+		EmptyShare:  0.5,
+		TopMaps:     []MapStats{{"q3dm17", 19, 2}, {"q3dm6", 0, 1}, {"q3tourney2", 0, 1}},
+		GameTypes:   map[string]int{"ffa": 2, "ctf": 1, "1v1": 1},
+		Busiest: []ServerSummary{
+			{"a:1", "A", "q3dm17", 16, 16},
+			{"c:1", "C", "q3dm17", 3, 8},
+			{"b:1", "B", "q3dm6", 0, 12},
+			{"d:1", "D", "q3tourney2", 0, 2},
+		},
+	}
+
+	result := ComputeGameStats(servers)
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+
+	summary := SummarizeStats(map[GameID]GameStats{"q3a": result, "openarena": {Players: 30}})
+	summaryFixture := StatsSummary{Games: 2, Servers: 5, ServersUp: 4, ServersDown: 1, Players: 49, Slots: 38, BusiestGame: []GamePlayers{{"openarena", 30}, {"q3a", 19}}}
+	if !reflect.DeepEqual(summaryFixture, summary) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, summaryFixture, summary))
+	}
+}
+
+func TestStatsCache(t *testing.T) {
+	table := MakeMemGameTable()
+	table.CreateGameEntry("q3a")
+	table.InsertServers("q3a", []ServerData{{Host: "a:1", Status: "UP", NumPlayers: 4, MaxPlayers: 8}})
+
+	cache := MakeStatsCache()
+	v, err := cache.Retrieve(table, "q3a")
+	if err != nil || v.Players != 4 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, 4, v))
+	}
+
+	table.InsertServers("q3a", []ServerData{{Host: "b:1", Status: "UP", NumPlayers: 2, MaxPlayers: 8}})
+	v, _ = cache.Retrieve(table, "q3a")
+	if v.Players != 6 {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, 6, v))
+	}
+
+	if _, err := cache.Retrieve(table, "missing"); err != errUnknownGameID {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errUnknownGameID.Error(), err))
+	}
+}
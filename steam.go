@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// vdfNode is a parsed block of Valve's KeyValues format. Values are either strings or nested nodes. Keys are lowercased since Steam treats them case-insensitively.
+type vdfNode map[string]interface{}
+
+func (n vdfNode) String(k string) (string, bool) {
+	v, ok := n[strings.ToLower(k)].(string)
+	return v, ok
+}
+
+func (n vdfNode) Node(k string) (vdfNode, bool) {
+	v, ok := n[strings.ToLower(k)].(vdfNode)
+	return v, ok
+}
+
+func readVDFToken(r *bufio.Reader) (token string, quoted bool, err error) {
+	var c rune
+	for {
+		c, _, err = r.ReadRune()
+		if err != nil {
+			return "", false, err
+		}
+		if unicode.IsSpace(c) {
+			continue
+		}
+		if c == '/' {
+			next, _, nextErr := r.ReadRune()
+			if nextErr == nil && next == '/' {
+				r.ReadString('\n')
+				continue
+			}
+			if nextErr == nil {
+				r.UnreadRune()
+			}
+		}
+		break
+	}
+
+	switch c {
+	case '{', '}':
+		return string(c), false, nil
+	case '"':
+		var b strings.Builder
+		for {
+			c, _, err = r.ReadRune()
+			if err != nil {
+				return "", false, errMalformedVDF
+			}
+			if c == '\\' {
+				next, _, nextErr := r.ReadRune()
+				if nextErr != nil {
+					return "", false, errMalformedVDF
+				}
+				switch next {
+				case 'n':
+					b.WriteRune('\n')
+				case 't':
+					b.WriteRune('\t')
+				default:
+					b.WriteRune(next)
+				}
+				continue
+			}
+			if c == '"' {
+				return b.String(), true, nil
+			}
+			b.WriteRune(c)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteRune(c)
+	for {
+		c, _, err = r.ReadRune()
+		if err != nil || unicode.IsSpace(c) || c == '{' || c == '}' || c == '"' {
+			if err == nil {
+				r.UnreadRune()
+			}
+			return b.String(), false, nil
+		}
+		b.WriteRune(c)
+	}
+}
+
+func parseVDFBlock(r *bufio.Reader, nested bool) (vdfNode, error) {
+	node := vdfNode{}
+	for {
+		key, quoted, err := readVDFToken(r)
+		if err == io.EOF && !nested {
+			return node, nil
+		}
+		if err != nil {
+			return nil, errMalformedVDF
+		}
+		if !quoted && key == "}" {
+			if !nested {
+				return nil, errMalformedVDF
+			}
+			return node, nil
+		}
+		if !quoted && key == "{" {
+			return nil, errMalformedVDF
+		}
+
+		value, vQuoted, err := readVDFToken(r)
+		if err != nil {
+			return nil, errMalformedVDF
+		}
+		switch {
+		case !vQuoted && value == "{":
+			child, childErr := parseVDFBlock(r, true)
+			if childErr != nil {
+				return nil, childErr
+			}
+			node[strings.ToLower(key)] = child
+		case !vQuoted && value == "}":
+			return nil, errMalformedVDF
+		default:
+			node[strings.ToLower(key)] = value
+		}
+	}
+}
+
+// parseVDF reads a KeyValues document such as libraryfolders.vdf or an appmanifest.
+func parseVDF(r io.Reader) (vdfNode, error) {
+	return parseVDFBlock(bufio.NewReader(r), false)
+}
+
+func parseVDFFile(path string) (vdfNode, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseVDF(f)
+}
+
+// SteamApp describes a single game installed in one of Steam libraries.
+type SteamApp struct {
+	AppID      string `json:"app_id"`
+	Name       string `json:"name"`
+	InstallDir string `json:"install_dir"`
+	Library    string `json:"library"`
+}
+
+// Dir returns the absolute path to the game's install directory.
+func (a SteamApp) Dir() string {
+	return filepath.Join(a.Library, "steamapps", "common", a.InstallDir)
+}
+
+// DefaultSteamRoots lists the usual locations of a Linux Steam installation.
+func DefaultSteamRoots() []string {
+	return []string{"~/.local/share/Steam", "~/.steam/steam", "~/.steam/root"}
+}
+
+// steamLibraryFolders returns the root itself followed by every extra library listed in its libraryfolders.vdf. Both the old ("1" "/path") and the new ("1" { "path" "/path" }) layouts are understood.
+func steamLibraryFolders(root string) []string {
+	output := []string{root}
+
+	for _, vdfPath := range []string{filepath.Join(root, "steamapps", "libraryfolders.vdf"), filepath.Join(root, "config", "libraryfolders.vdf")} {
+		doc, err := parseVDFFile(vdfPath)
+		if err != nil {
+			continue
+		}
+
+		folders, ok := doc.Node("libraryfolders")
+		if !ok {
+			folders, ok = doc.Node("LibraryFolders")
+		}
+		if !ok {
+			continue
+		}
+
+		for _, v := range folders {
+			var libPath string
+			switch entry := v.(type) {
+			case string:
+				if filepath.IsAbs(entry) {
+					libPath = entry
+				}
+			case vdfNode:
+				libPath, _ = entry.String("path")
+			}
+			if libPath != "" {
+				output = append(output, libPath)
+			}
+		}
+	}
+
+	return output
+}
+
+// steamLibraryApps reads all appmanifest_<id>.acf files in the library.
+func steamLibraryApps(library string) map[string]SteamApp {
+	output := map[string]SteamApp{}
+
+	manifests, _ := filepath.Glob(filepath.Join(library, "steamapps", "appmanifest_*.acf"))
+	for _, manifestPath := range manifests {
+		doc, err := parseVDFFile(manifestPath)
+		if err != nil {
+			continue
+		}
+
+		state, ok := doc.Node("AppState")
+		if !ok {
+			continue
+		}
+
+		appID, _ := state.String("appid")
+		installDir, _ := state.String("installdir")
+		if appID == "" || installDir == "" {
+			continue
+		}
+		name, _ := state.String("name")
+
+		output[appID] = SteamApp{AppID: appID, Name: name, InstallDir: installDir, Library: library}
+	}
+
+	return output
+}
+
+// ScanSteamLibraries finds every installed Steam app across the given Steam roots and their extra libraries. Roots that do not exist are skipped.
+func ScanSteamLibraries(roots []string) map[string]SteamApp {
+	output := map[string]SteamApp{}
+	seen := map[string]bool{}
+
+	for _, root := range roots {
+		root = ExpandHome(root)
+		for _, library := range steamLibraryFolders(root) {
+			realPath, err := filepath.EvalSymlinks(library)
+			if err != nil || seen[realPath] {
+				continue
+			}
+			seen[realPath] = true
+
+			for k, v := range steamLibraryApps(library) {
+				if _, exists := output[k]; !exists {
+					output[k] = v
+				}
+			}
+		}
+	}
+
+	return output
+}
+
+// steamRelativeExecutable extracts the game executable's path relative to its install directory from the configured settings.
+func steamRelativeExecutable(path string, workdir string) string {
+	path = filepath.ToSlash(ExpandHome(path))
+	workdir = filepath.ToSlash(ExpandHome(workdir))
+
+	if workdir != "" && strings.HasPrefix(path, strings.TrimSuffix(workdir, "/")+"/") {
+		return strings.TrimPrefix(path, strings.TrimSuffix(workdir, "/")+"/")
+	}
+
+	const commonDir = "/steamapps/common/"
+	if i := strings.Index(path, commonDir); i != -1 {
+		tail := path[i+len(commonDir):]
+		if j := strings.Index(tail, "/"); j != -1 {
+			return tail[j+1:]
+		}
+	}
+
+	if path == "" {
+		return ""
+	}
+
+	return filepath.Base(path)
+}
+
+// SteamGamePaths computes the path and workdir settings for the installed app, keeping the executable that the settings currently point to.
+func SteamGamePaths(app SteamApp, settings SettingsMap) (path string, workdir string) {
+	workdir = app.Dir()
+
+	rel := steamRelativeExecutable(settings["path"], settings["workdir"])
+	if rel == "" {
+		return "", workdir
+	}
+
+	return filepath.Join(workdir, filepath.FromSlash(rel)), workdir
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func writeTestFile(t *testing.T, path string, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseVDF(t *testing.T) {
+	input := `
+// Comment line
+"AppState"
+{
+	"appid"		"730"
+	"Name"		"Counter-Strike: \"Global\" Offensive"
+	"UserConfig"
+	{
+		"language"		"english"
+	}
+}
+`
+	fixture := vdfNode{"appstate": vdfNode{"appid": "730", "name": `Counter-Strike: "Global" Offensive`, "userconfig": vdfNode{"language": "english"}}}
+
+	result, err := parseVDF(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(goutil.ErrorOutJSON(err, fixture, result))
+	}
+
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+
+	if _, err := parseVDF(strings.NewReader(`"AppState" { "appid" "730"`)); err != errMalformedVDF {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errMalformedVDF, err))
+	}
+}
+
+func makeFakeSteamTree(t *testing.T) (root string, library string) {
+	base := t.TempDir()
+	root = filepath.Join(base, "Steam")
+	library = filepath.Join(base, "games", "SteamLibrary")
+
+	writeTestFile(t, filepath.Join(root, "steamapps", "libraryfolders.vdf"), `
+"libraryfolders"
+{
+	"0"
+	{
+		"path"		"`+root+`"
+	}
+	"1"
+	{
+		"path"		"`+library+`"
+		"apps"
+		{
+			"730"		"0"
+		}
+	}
+}
+`)
+	writeTestFile(t, filepath.Join(root, "steamapps", "appmanifest_240.acf"), `
+"AppState"
+{
+	"appid"		"240"
+	"name"		"Counter-Strike: Source"
+	"installdir"		"Counter-Strike Source"
+}
+`)
+	writeTestFile(t, filepath.Join(library, "steamapps", "appmanifest_730.acf"), `
+"AppState"
+{
+	"appid"		"730"
+	"name"		"Counter-Strike: Global Offensive"
+	"installdir"		"Counter-Strike Global Offensive"
+}
+`)
+
+	return root, library
+}
+
+func TestScanSteamLibraries(t *testing.T) {
+	root, library := makeFakeSteamTree(t)
+
+	fixture := map[string]SteamApp{
+		"240": {AppID: "240", Name: "Counter-Strike: Source", InstallDir: "Counter-Strike Source", Library: root},
+		"730": {AppID: "730", Name: "Counter-Strike: Global Offensive", InstallDir: "Counter-Strike Global Offensive", Library: library},
+	}
+
+	result := ScanSteamLibraries([]string{root, filepath.Join(root, "missing")})
+
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+}
+
+func TestLegacySteamLibraryFolders(t *testing.T) {
+	root := t.TempDir()
+	library := filepath.Join(root, "extra")
+	writeTestFile(t, filepath.Join(root, "steamapps", "libraryfolders.vdf"), `
+"LibraryFolders"
+{
+	"TimeNextStatsReport"		"1500000000"
+	"ContentStatsID"		"-1234"
+	"1"		"`+library+`"
+}
+`)
+
+	fixture := []string{root, library}
+	result := steamLibraryFolders(root)
+
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+}
+
+func TestDetectSteamInstallations(t *testing.T) {
+	root, library := makeFakeSteamTree(t)
+
+	c := StartCore(MakeMemGameTable())
+	c.SteamRoots = []string{root}
+
+	games := map[GameID]GameInfo{
+		"csgo":   {Name: "Counter-Strike: Global Offensive", SteamAppID: "730"},
+		"tf":     {Name: "Team Fortress 2", SteamAppID: "440"},
+		"q3a":    {Name: "Quake III Arena"},
+		"hl2dm2": {Name: "Broken settings", SteamAppID: "240"},
+	}
+	for id, info := range games {
+		c.GameTable.CreateGameEntry(id)
+		c.GameTable.SetGameInfo(id, info)
+	}
+	c.GameTable.SetSetting("csgo", "path", "~/.local/share/Steam/steamapps/common/Counter-Strike Global Offensive/csgo_linux")
+	c.GameTable.SetSetting("csgo", "workdir", "~/.local/share/Steam/steamapps/common/Counter-Strike Global Offensive")
+
+	for id, err := range c.DetectInstallations(c.GameTable.AllGames()) {
+		if err != nil {
+			t.Fatal(id, err)
+		}
+	}
+
+	csgoDir := filepath.Join(library, "steamapps", "common", "Counter-Strike Global Offensive")
+	fixture := map[GameID]InstallInfo{
+		"csgo":   {Installed: true, Source: InstallSourceSteam, Path: filepath.Join(csgoDir, "csgo_linux"), Workdir: csgoDir},
+		"tf":     {Installed: false, Source: InstallSourceSteam},
+		"hl2dm2": {Installed: true, Source: InstallSourceSteam, Workdir: filepath.Join(root, "steamapps", "common", "Counter-Strike Source")},
+	}
+
+	result := map[GameID]InstallInfo{}
+	for id := range games {
+		if v, exists := c.Installs.Retrieve(id); exists {
+			result[id] = v
+		}
+	}
+
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+
+	settings, _ := c.GameTable.Settings("csgo")
+	if settings["path"] != fixture["csgo"].Path || settings["workdir"] != fixture["csgo"].Workdir {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture["csgo"], settings))
+	}
+}
+
+func TestDetectNewInstallationKeepsSettings(t *testing.T) {
+	root, library := makeFakeSteamTree(t)
+
+	c := StartCore(MakeMemGameTable())
+	c.SteamRoots = []string{root}
+
+	c.GameTable.CreateGameEntry("csgo")
+	c.GameTable.SetGameInfo("csgo", GameInfo{Name: "Counter-Strike: Global Offensive", SteamAppID: "730"})
+	c.GameTable.SetSetting("csgo", "path", "csgo_linux")
+	c.GameTable.SetSetting("csgo", "workdir", "/opt/csgo")
+
+	if err := c.DetectNewInstallations([]GameID{"csgo"}, map[GameID]map[string]bool{"csgo": {"workdir": true}})["csgo"]; err != nil {
+		t.Fatal(err)
+	}
+
+	csgoDir := filepath.Join(library, "steamapps", "common", "Counter-Strike Global Offensive")
+	fixture := SettingsMap{"path": filepath.Join(csgoDir, "csgo_linux"), "workdir": "/opt/csgo"}
+	result, _ := c.GameTable.Settings("csgo")
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+}
+
+func TestDetectSteamInstallationFromCatalogDefaults(t *testing.T) {
+	root, library := makeFakeSteamTree(t)
+
+	c := StartCore(MakeMemGameTable())
+	c.SteamRoots = []string{root}
+	catalog, err := ParseCatalog([]byte(`[csgo]
+name = "Counter-Strike: Global Offensive"
+steam_app_id = "730"
+settings = ["path", "workdir"]
+`), []byte(`[csgo]
+path = "~/.local/share/Steam/steamapps/common/Counter-Strike Global Offensive/csgo_linux"
+workdir = "~/.local/share/Steam/steamapps/common/Counter-Strike Global Offensive"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetCatalog(catalog)
+
+	c.GameTable.CreateGameEntry("csgo")
+	c.GameTable.SetGameInfo("csgo", GameInfo{Name: "Counter-Strike: Global Offensive", SteamAppID: "730"})
+
+	if err := c.DetectInstallations([]GameID{"csgo"})["csgo"]; err != nil {
+		t.Fatal(err)
+	}
+
+	csgoDir := filepath.Join(library, "steamapps", "common", "Counter-Strike Global Offensive")
+	fixture := SettingsMap{"path": filepath.Join(csgoDir, "csgo_linux"), "workdir": csgoDir}
+	result, _ := c.GameTable.Settings("csgo")
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// loadJSONFile decodes the file into v. A missing file leaves v untouched.
+func loadJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// saveJSONFile atomically replaces the file with v encoded as JSON. Empty path means the data is kept in memory only.
+func saveJSONFile(path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+// statePath returns the path of a state file in the state directory, or an empty path if state is not persisted.
+func statePath(dir string, name string) string {
+	if dir == "" {
+		return ""
+	}
+
+	return filepath.Join(dir, name)
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// postWebhooks sends the payload as JSON to every URL in the background. onError is called for each failed delivery.
+func postWebhooks(urls []string, payload interface{}, onError func(string, error)) {
+	if len(urls) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		for _, url := range urls {
+			onError(url, err)
+		}
+		return
+	}
+
+	for _, url := range urls {
+		go func(url string) {
+			resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(data))
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode >= 300 {
+					err = errWebhookStatus
+				}
+			}
+			if err != nil {
+				onError(url, err)
+			}
+		}(url)
+	}
+}
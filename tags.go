@@ -0,0 +1,120 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// TagInstalled is given to games whose installation was found.
+const TagInstalled = "installed"
+
+// NormalizeTags lowercases custom tags and drops duplicates. Tags such as "engine:quake" or "genre:shooter" use a prefix to name the kind of grouping.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	output := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(tag)
+		if tag == "" || strings.ContainsAny(tag, " \t\r\n") {
+			return nil, errInvalidTag
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			output = append(output, tag)
+		}
+	}
+	sort.Strings(output)
+
+	return output, nil
+}
+
+// gameTags merges the catalog tags of the game with its custom tags and the installed tag.
+func (c *Core) gameTags(id GameID, info GameInfo) []string {
+	var tags []string
+	if g, exists := c.catalog().Game(info.CatalogBase(id)); exists {
+		tags = append(tags, g.Tags...)
+	}
+	tags = append(tags, info.Tags...)
+	if install, exists := c.Installs.Retrieve(id); exists && install.Installed {
+		tags = append(tags, TagInstalled)
+	}
+
+	output, _ := NormalizeTags(tags)
+
+	return output
+}
+
+// GameTags returns all tags of the game.
+func (c *Core) GameTags(id GameID) ([]string, error) {
+	info, err := c.GameTable.GameInfo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.gameTags(id, info), nil
+}
+
+// SetGameTags replaces the custom tags of the game.
+func (c *Core) SetGameTags(id GameID, tags []string) error {
+	tags, err := NormalizeTags(tags)
+	if err != nil {
+		return err
+	}
+
+	info, err := c.GameTable.GameInfo(id)
+	if err != nil {
+		return err
+	}
+	info.Tags = tags
+
+	return c.GameTable.SetGameInfo(id, info)
+}
+
+func hasTags(tags []string, required []string) bool {
+	for _, r := range required {
+		i := sort.SearchStrings(tags, r)
+		if i == len(tags) || tags[i] != r {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchGames returns the sorted IDs of the games that have all of the tags.
+func (c *Core) MatchGames(tags []string) ([]GameID, error) {
+	required, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	output := c.GameTable.MatchGameEntries(func(id GameID, e *GameEntry) bool { return hasTags(c.gameTags(id, e.Info), required) })
+	sort.Slice(output, func(i, j int) bool { return output[i] < output[j] })
+
+	return output, nil
+}
+
+// GroupGames returns the games tagged with the group, failing if there are none.
+func (c *Core) GroupGames(group string) ([]GameID, error) {
+	output, err := c.MatchGames([]string{group})
+	if err != nil {
+		return nil, err
+	}
+	if len(output) == 0 {
+		return nil, errNoSuchGroup
+	}
+
+	return output, nil
+}
+
+// Groups counts the games with each tag.
+func (c *Core) Groups() map[string]int {
+	output := map[string]int{}
+	c.GameTable.MatchGameEntries(func(id GameID, e *GameEntry) bool {
+		for _, tag := range c.gameTags(id, e.Info) {
+			output[tag]++
+		}
+		return false
+	})
+
+	return output
+}
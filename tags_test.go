@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	result, err := NormalizeTags([]string{"Genre:Shooter", "lan", "genre:shooter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture := []string{"genre:shooter", "lan"}
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+
+	for _, v := range [][]string{{""}, {"two words"}} {
+		if _, err := NormalizeTags(v); err != errInvalidTag {
+			t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errInvalidTag, err))
+		}
+	}
+}
+
+func TestMatchGames(t *testing.T) {
+	c := makeTestCatalogCore(t)
+	c.catalog().Games["tf"] = CatalogGame{Name: "Team Fortress 2", Tags: []string{"engine:source", "genre:shooter"}}
+	c.catalog().Games["minetest"] = CatalogGame{Name: "Minetest", Tags: []string{"engine:minetest", "genre:sandbox"}}
+	for _, id := range []GameID{"tf", "minetest", "custom"} {
+		c.GameTable.CreateGameEntry(id)
+	}
+	c.GameTable.SetGameInfo("custom", GameInfo{Name: "Custom"})
+	if err := c.SetGameTags("custom", []string{"Genre:Shooter", "lan"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetGameTags("tf", []string{"bad tag"}); err != errInvalidTag {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errInvalidTag, err))
+	}
+	c.Installs.Insert("minetest", InstallInfo{Installed: true})
+
+	tags, _ := c.GameTags("custom")
+	expectGameTableValue(t, "custom tags", []string{"genre:shooter", "lan"}, tags)
+	info, _ := c.GameTable.GameInfo("custom")
+	expectGameTableValue(t, "custom name", "Custom", info.Name)
+
+	for _, v := range []struct {
+		Tags   []string
+		Result []GameID
+	}{
+		{[]string{"genre:shooter"}, []GameID{"custom", "tf"}},
+		{[]string{"GENRE:SHOOTER", "engine:source"}, []GameID{"tf"}},
+		{[]string{TagInstalled}, []GameID{"minetest"}},
+		{nil, []GameID{"custom", "minetest", "tf"}},
+		{[]string{"engine:quake"}, []GameID{}},
+	} {
+		result, err := c.MatchGames(v.Tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectGameTableValue(t, "MatchGames", v.Result, result)
+	}
+
+	if _, err := c.GroupGames("engine:quake"); err != errNoSuchGroup {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, errNoSuchGroup, err))
+	}
+	group, _ := c.GroupGames("engine:source")
+	expectGameTableValue(t, "GroupGames", []GameID{"tf"}, group)
+
+	groups := c.Groups()
+	fixture := map[string]int{"engine:source": 1, "engine:minetest": 1, "genre:shooter": 2, "genre:sandbox": 1, "lan": 1, TagInstalled: 1}
+	if !reflect.DeepEqual(fixture, groups) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, groups))
+	}
+}
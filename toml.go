@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tomlTables maps full table names such as "csgo" or "csgo.proxy" to their keys. Keys before the first header belong to the "" table.
+type tomlTables map[string]map[string]interface{}
+
+// decodeTOML parses the TOML subset used by the asset files: table headers, bare keys, basic and literal strings, integers, floats, booleans and arrays of those.
+// Tables are kept flat so that a table may share its name with a key of its parent, as "proxy" does in game_lists.toml.
+// Standard TOML forbids that, so spec-compliant decoders such as BurntSushi/toml reject the catalog ("Key 'rigsofrods.proxy' has already been defined"). Switching to one needs a catalog format change first.
+// The table names are also returned in the order they first appear.
+func decodeTOML(data []byte) (tomlTables, []string, error) {
+	output := tomlTables{"": {}}
+	order := []string{""}
+	table := ""
+
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripTOMLComment(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, nil, fmt.Errorf("line %d: %v", lineNo, errMalformedTOML)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			if table == "" {
+				return nil, nil, fmt.Errorf("line %d: %v", lineNo, errMalformedTOML)
+			}
+			if _, exists := output[table]; !exists {
+				output[table] = map[string]interface{}{}
+				order = append(order, table)
+			}
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq <= 0 {
+			return nil, nil, fmt.Errorf("line %d: %v", lineNo, errMalformedTOML)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if unquoted, err := strconv.Unquote(key); err == nil {
+			key = unquoted
+		}
+		raw := strings.TrimSpace(line[eq+1:])
+
+		// Arrays may span several lines.
+		for strings.HasPrefix(raw, "[") && !tomlArrayClosed(raw) && i+1 < len(lines) {
+			i++
+			raw += " " + strings.TrimSpace(stripTOMLComment(lines[i]))
+		}
+
+		v, rest, err := parseTOMLValue(raw)
+		if err != nil || strings.TrimSpace(rest) != "" {
+			return nil, nil, fmt.Errorf("line %d: %v", lineNo, errMalformedTOML)
+		}
+		if _, exists := output[table][key]; exists {
+			return nil, nil, fmt.Errorf("line %d: duplicate key %q", lineNo, key)
+		}
+		output[table][key] = v
+	}
+
+	return output, order, nil
+}
+
+func stripTOMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+func tomlArrayClosed(raw string) bool {
+	_, _, err := parseTOMLValue(raw)
+	return err == nil
+}
+
+func parseTOMLValue(s string) (interface{}, string, error) {
+	s = strings.TrimLeft(s, " \t")
+	if s == "" {
+		return nil, s, errMalformedTOML
+	}
+
+	switch s[0] {
+	case '"':
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == '"' {
+				v, err := strconv.Unquote(s[:i+1])
+				return v, s[i+1:], err
+			}
+		}
+		return nil, s, errMalformedTOML
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, s, errMalformedTOML
+		}
+		return s[1 : end+1], s[end+2:], nil
+	case '[':
+		output := []interface{}{}
+		rest := strings.TrimLeft(s[1:], " \t")
+		for {
+			if strings.HasPrefix(rest, "]") {
+				return output, rest[1:], nil
+			}
+			v, r, err := parseTOMLValue(rest)
+			if err != nil {
+				return nil, s, err
+			}
+			output = append(output, v)
+			rest = strings.TrimLeft(r, " \t")
+			if strings.HasPrefix(rest, ",") {
+				rest = strings.TrimLeft(rest[1:], " \t")
+			} else if !strings.HasPrefix(rest, "]") {
+				return nil, s, errMalformedTOML
+			}
+		}
+	}
+
+	end := strings.IndexAny(s, ",] \t")
+	if end < 0 {
+		end = len(s)
+	}
+	token, rest := s[:end], s[end:]
+	switch token {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	if v, err := strconv.ParseInt(strings.Replace(token, "_", "", -1), 10, 64); err == nil {
+		return v, rest, nil
+	}
+	if v, err := strconv.ParseFloat(strings.Replace(token, "_", "", -1), 64); err == nil {
+		return v, rest, nil
+	}
+
+	return nil, s, errMalformedTOML
+}
+
+// quoteTOMLString writes s as a TOML basic string. Unlike strconv.Quote it only uses escapes that TOML knows: other control characters become \uXXXX and everything else is written as is.
+func quoteTOMLString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+func encodeTOMLKey(k string) string {
+	for _, c := range k {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return quoteTOMLString(k)
+		}
+	}
+	if k == "" {
+		return `""`
+	}
+
+	return k
+}
+
+func encodeTOMLValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return quoteTOMLString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []string:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			items = append(items, item)
+		}
+		return encodeTOMLValue(items)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, encodeTOMLValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+
+	return quoteTOMLString(fmt.Sprint(v))
+}
+
+// encodeTOML writes the tables in the given order, separating top-level tables with blank lines. Keys listed in keyOrder come first, the rest follow sorted.
+func encodeTOML(tables tomlTables, order []string, keyOrder []string) []byte {
+	var b bytes.Buffer
+
+	rank := map[string]int{}
+	for i, k := range keyOrder {
+		rank[k] = i - len(keyOrder)
+	}
+
+	for _, name := range order {
+		table, exists := tables[name]
+		if !exists || (name == "" && len(table) == 0) {
+			continue
+		}
+		if name != "" {
+			if b.Len() > 0 && !strings.Contains(name, ".") {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "[%s]\n", name)
+		}
+
+		keys := make([]string, 0, len(table))
+		for k := range table {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if rank[keys[i]] != rank[keys[j]] {
+				return rank[keys[i]] < rank[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s = %s\n", encodeTOMLKey(k), encodeTOMLValue(table[k]))
+		}
+	}
+
+	return b.Bytes()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skybon/goutil"
+)
+
+func TestDecodeTOML(t *testing.T) {
+	input := `# Catalog
+top = 1
+
+[csgo]
+name = "Counter-Strike: \"GO\"" # trailing comment
+proxy = 'qstat_output'
+steam_launch = true
+ratio = 0.5
+master_uri = ["master://a:27011",
+	"master://b:27011", # second
+]
+[csgo.proxy]
+master_type = "STM"
+"quoted key" = -3
+`
+
+	fixture := tomlTables{
+		"":           {"top": int64(1)},
+		"csgo":       {"name": `Counter-Strike: "GO"`, "proxy": "qstat_output", "steam_launch": true, "ratio": 0.5, "master_uri": []interface{}{"master://a:27011", "master://b:27011"}},
+		"csgo.proxy": {"master_type": "STM", "quoted key": int64(-3)},
+	}
+
+	result, order, err := decodeTOML([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fixture, result) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+	if orderFixture := []string{"", "csgo", "csgo.proxy"}; !reflect.DeepEqual(orderFixture, order) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, orderFixture, order))
+	}
+
+	for _, invalid := range []string{"[csgo", "key", "key = ", `key = "open`, "key = [1, 2", "key = bare", "a = 1\na = 2", "[[array]]"} {
+		if _, _, err := decodeTOML([]byte(invalid)); err == nil {
+			t.Error("expected error for", invalid)
+		}
+	}
+}
+
+func TestEncodeTOMLString(t *testing.T) {
+	input := "bell\a nul\x00 del\x7f tab\t \"quoted\" back\\slash Мой сервер"
+	fixture := `"bell\u0007 nul\u0000 del\u007F tab\t \"quoted\" back\\slash Мой сервер"`
+	if result := encodeTOMLValue(input); result != fixture {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, result))
+	}
+
+	tables, _, err := decodeTOML([]byte("key = " + encodeTOMLValue(input) + "\n" + encodeTOMLKey("odd\akey") + " = 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixture := (tomlTables{"": {"key": input, "odd\akey": int64(1)}}); !reflect.DeepEqual(fixture, tables) {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, fixture, tables))
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/skybon/semaphore"
+)
+
+// DefaultTrashRetention is how long deleted games can be restored.
+const DefaultTrashRetention = 7 * 24 * time.Hour
+
+// TrashEntry is a deleted game kept for restoring. DeletedBy is the name the client gave, Address is where the request came from.
+type TrashEntry struct {
+	ID         string        `json:"id"`
+	GameID     GameID        `json:"game_id"`
+	DeletedAt  time.Time     `json:"deleted_at"`
+	DeletedBy  string        `json:"deleted_by"`
+	Address    string        `json:"address"`
+	Game       gameEntryDump `json:"game"`
+	BlockRules *BlockRules   `json:"block_rules,omitempty"`
+}
+
+type trashDump struct {
+	Entries []TrashEntry `json:"entries"`
+}
+
+// Trash keeps deleted games until they are restored, purged or older than Retention.
+type Trash struct {
+	Retention time.Duration
+
+	path      string
+	entries   map[string]TrashEntry
+	semaphore semaphore.Semaphore
+}
+
+func (c *Trash) all() []TrashEntry {
+	output := make([]TrashEntry, 0, len(c.entries))
+	for _, v := range c.entries {
+		output = append(output, v)
+	}
+	sort.Slice(output, func(i, j int) bool { return output[i].DeletedAt.After(output[j].DeletedAt) })
+
+	return output
+}
+
+func (c *Trash) save() error {
+	return saveJSONFile(c.path, trashDump{Entries: c.all()})
+}
+
+func (c *Trash) expire(t time.Time) (expired []TrashEntry) {
+	for id, v := range c.entries {
+		if t.Sub(v.DeletedAt) > c.Retention {
+			expired = append(expired, v)
+			delete(c.entries, id)
+		}
+	}
+
+	return expired
+}
+
+// expireNow purges the entries older than Retention. Saving is best effort, the entries are gone from memory either way.
+func (c *Trash) expireNow() {
+	if len(c.expire(time.Now())) > 0 {
+		c.save()
+	}
+}
+
+// All returns the entries that have not expired, most recently deleted first.
+func (c *Trash) All() (output []TrashEntry) {
+	c.semaphore.Exec(func() {
+		c.expireNow()
+		output = c.all()
+	})
+
+	return output
+}
+
+// Add puts a deleted game into the trash. The entry ID is made of the game ID and the deletion time.
+func (c *Trash) Add(e TrashEntry) (output TrashEntry, err error) {
+	c.semaphore.Exec(func() {
+		c.expireNow()
+		e.ID = fmt.Sprintf("%s-%d", e.GameID, e.DeletedAt.UnixNano())
+		c.entries[e.ID] = e
+		output = e
+		err = c.save()
+	})
+
+	return output, err
+}
+
+// Retrieve returns the entry unless it has expired.
+func (c *Trash) Retrieve(id string) (e TrashEntry, exists bool) {
+	c.semaphore.Exec(func() {
+		c.expireNow()
+		e, exists = c.entries[id]
+	})
+
+	return e, exists
+}
+
+// Remove purges the entry.
+func (c *Trash) Remove(id string) (err error) {
+	c.semaphore.Exec(func() {
+		if _, exists := c.entries[id]; !exists {
+			err = errNoSuchTrashEntry
+			return
+		}
+		delete(c.entries, id)
+		err = c.save()
+	})
+
+	return err
+}
+
+// LoadTrash reads deleted games from path. Empty path keeps them in memory only.
+func LoadTrash(path string) (*Trash, error) {
+	c := &Trash{Retention: DefaultTrashRetention, path: path, entries: map[string]TrashEntry{}, semaphore: semaphore.MakeSemaphore(1)}
+
+	var dump trashDump
+	if err := loadJSONFile(path, &dump); err != nil {
+		return nil, err
+	}
+	for _, v := range dump.Entries {
+		c.entries[v.ID] = v
+	}
+
+	return c, nil
+}
+
+// MakeTrash creates an empty in-memory trash.
+func MakeTrash() *Trash {
+	c, _ := LoadTrash("")
+	return c
+}
+
+// removeGameData forgets everything kept about the game outside of the game table.
+func (c *Core) removeGameData(id GameID) {
+	c.Installs.Remove(id)
+	c.History.RemoveGame(id)
+	c.Stats.Remove(id)
+	c.Players.RemoveGame(id)
+	c.Alerts.RemoveGame(id)
+	c.Blocks.RemoveGame(id)
+}
+
+// TrashGame removes the game and keeps its info, settings, favorites and block rules in the trash. Servers and their history are not kept.
+func (c *Core) TrashGame(id GameID, deletedBy string, address string) (TrashEntry, error) {
+	e, err := c.GameTable.CopyGameEntry(id, false)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	favorites, err := c.GameTable.Favorites(id)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+
+	entry := TrashEntry{GameID: id, DeletedAt: time.Now(), DeletedBy: deletedBy, Address: address, Game: gameEntryDump{Info: e.Info, Settings: e.Settings.AllSettings(), Favorites: favorites}}
+	if rules := c.Blocks.Rules(id); !rules.IsEmpty() {
+		entry.BlockRules = &rules
+	}
+
+	if err := c.GameTable.RemoveGameEntry(id); err != nil {
+		return TrashEntry{}, err
+	}
+	c.removeGameData(id)
+
+	return c.Trash.Add(entry)
+}
+
+// RestoreGame brings a deleted game back and removes it from the trash. It fails with errGameExists if a game with the same ID was created in the meantime.
+func (c *Core) RestoreGame(trashID string) (GameID, error) {
+	e, exists := c.Trash.Retrieve(trashID)
+	if !exists {
+		return "", errNoSuchTrashEntry
+	}
+
+	if err := restoreGameTable(c.GameTable, gameTableDump{Games: map[GameID]gameEntryDump{e.GameID: e.Game}}); err != nil {
+		return "", err
+	}
+	if e.BlockRules != nil {
+		if err := c.Blocks.SetRules(e.GameID, *e.BlockRules); err != nil {
+			return e.GameID, err
+		}
+	}
+	ids := []GameID{e.GameID}
+	c.DetectNewInstallations(ids, c.storedSettingKeys(ids))
+
+	return e.GameID, c.Trash.Remove(trashID)
+}
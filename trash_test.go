@@ -0,0 +1,110 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/skybon/goutil"
+)
+
+func TestTrashRestore(t *testing.T) {
+	c := StartCore(MakeMemGameTable())
+	c.SteamRoots = nil
+	info := GameInfo{Name: "Quake III Arena", Proxy: ProxyQStatOutput, Adapter: AdapterQStatXML, Tags: []string{"lan"}}
+	c.GameTable.CreateGameEntry("q3a")
+	c.GameTable.SetGameInfo("q3a", info)
+	c.GameTable.SetSetting("q3a", "path", "quake3")
+	c.GameTable.SetFavorite("q3a", Favorite{Host: "1.2.3.4:27960", Label: "Home"})
+	c.GameTable.InsertServers("q3a", []ServerData{{Host: "1.2.3.4:27960"}})
+	c.Blocks.SetRules("q3a", BlockRules{ZeroPing: true})
+
+	e, err := c.TrashGame("q3a", "alice", "127.0.0.1:5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectGameTableValue(t, "CheckGameEntry after delete", false, c.GameTable.CheckGameEntry("q3a"))
+	expectGameTableValue(t, "block rules after delete", BlockRules{}, c.Blocks.Rules("q3a"))
+	expectGameTableValue(t, "deleted by", "alice", e.DeletedBy)
+	expectGameTableValue(t, "address", "127.0.0.1:5000", e.Address)
+	expectGameTableValue(t, "trash", []TrashEntry{e}, c.Trash.All())
+
+	_, err = c.TrashGame("q3a", "alice", "")
+	expectGameTableError(t, "TrashGame missing", errUnknownGameID, err)
+
+	c.GameTable.CreateGameEntry("q3a")
+	_, err = c.RestoreGame(e.ID)
+	expectGameTableError(t, "RestoreGame collision", errGameExists, err)
+	c.GameTable.RemoveGameEntry("q3a")
+
+	id, err := c.RestoreGame(e.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectGameTableValue(t, "restored ID", GameID("q3a"), id)
+	restored, _ := c.GameTable.GameInfo("q3a")
+	expectGameTableValue(t, "restored info", info, restored)
+	settings, _ := c.GameTable.Settings("q3a")
+	expectGameTableValue(t, "restored settings", SettingsMap{"path": "quake3"}, settings)
+	favorites, _ := c.GameTable.Favorites("q3a")
+	expectGameTableValue(t, "restored favorites", []Favorite{{Host: "1.2.3.4:27960", Label: "Home"}}, favorites)
+	servers, _ := c.GameTable.AllServers("q3a")
+	expectGameTableValue(t, "restored servers", 0, len(servers))
+	expectGameTableValue(t, "restored block rules", BlockRules{ZeroPing: true}, c.Blocks.Rules("q3a"))
+	expectGameTableValue(t, "trash after restore", 0, len(c.Trash.All()))
+
+	_, err = c.RestoreGame(e.ID)
+	expectGameTableError(t, "RestoreGame twice", errNoSuchTrashEntry, err)
+}
+
+func TestTrashRestoreKeepsPaths(t *testing.T) {
+	root, _ := makeFakeSteamTree(t)
+
+	c := StartCore(MakeMemGameTable())
+	c.SteamRoots = []string{root}
+	c.GameTable.CreateGameEntry("csgo")
+	c.GameTable.SetGameInfo("csgo", GameInfo{Name: "Counter-Strike: Global Offensive", SteamAppID: "730"})
+	c.GameTable.SetSetting("csgo", "path", "/opt/csgo/csgo_linux")
+	c.GameTable.SetSetting("csgo", "workdir", "/opt/csgo")
+
+	e, err := c.TrashGame("csgo", "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RestoreGame(e.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, _ := c.GameTable.Settings("csgo")
+	expectGameTableValue(t, "restored settings", SettingsMap{"path": "/opt/csgo/csgo_linux", "workdir": "/opt/csgo"}, settings)
+}
+
+func TestTrashRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trash.json")
+	c, err := LoadTrash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Retention = time.Hour
+
+	old, _ := c.Add(TrashEntry{GameID: "q3a", DeletedAt: time.Now().Add(-2 * time.Hour)})
+	recent, _ := c.Add(TrashEntry{GameID: "q3a", DeletedAt: time.Now().Add(-time.Minute)})
+	newest, _ := c.Add(TrashEntry{GameID: "openarena", DeletedAt: time.Now()})
+
+	if _, exists := c.Retrieve(old.ID); exists {
+		t.Error("expired entry is still retrievable")
+	}
+
+	loaded, err := LoadTrash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := loaded.All()
+	if len(result) != 2 || result[0].ID != newest.ID || result[1].ID != recent.ID {
+		t.Error(goutil.ErrorOutJSON(goutil.ErrMismatch, []TrashEntry{newest, recent}, result))
+	}
+
+	expectGameTableError(t, "Remove", nil, loaded.Remove(recent.ID))
+	expectGameTableError(t, "Remove twice", errNoSuchTrashEntry, loaded.Remove(recent.ID))
+	expectGameTableValue(t, "All after purge", 1, len(loaded.All()))
+}
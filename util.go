@@ -1,6 +1,24 @@
 package main
 
-import "strings"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExpandHome replaces the leading tilde in path with the user's home directory.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
 
 // ParseHostPort splits hostname into host and port parts.
 func ParseHostPort(server string) (host string, port string, is6 bool, err error) {
@@ -0,0 +1,124 @@
+package main
+
+// MatchWildcard reports whether name matches the shell pattern. The syntax is that of path.Match ("*", "?", "[...]" classes and "\" escapes), but "/" is an ordinary character, so "*" matches across it. Malformed patterns return errBadPattern.
+func MatchWildcard(pattern, name string) (bool, error) {
+	p := []rune(pattern)
+	if err := checkWildcard(p); err != nil {
+		return false, err
+	}
+
+	return matchWildcard(p, []rune(name)), nil
+}
+
+func checkWildcard(p []rune) error {
+	for len(p) > 0 {
+		if p[0] == '*' {
+			p = p[1:]
+			continue
+		}
+		n, _, err := matchWildcardRune(p, 0)
+		if err != nil {
+			return err
+		}
+		p = p[n:]
+	}
+
+	return nil
+}
+
+// matchWildcard expects a pattern that passed checkWildcard.
+func matchWildcard(p, s []rune) bool {
+	var px, sx int
+	starPx, starSx := -1, 0
+	for px < len(p) || sx < len(s) {
+		if px < len(p) {
+			if p[px] == '*' {
+				starPx, starSx = px, sx
+				px++
+				continue
+			}
+			if sx < len(s) {
+				if n, ok, _ := matchWildcardRune(p[px:], s[sx]); ok {
+					px += n
+					sx++
+					continue
+				}
+			}
+		}
+		if starPx >= 0 && starSx < len(s) {
+			starSx++
+			px, sx = starPx+1, starSx
+			continue
+		}
+		return false
+	}
+
+	return true
+}
+
+// matchWildcardRune matches c against the single-rune token at the start of p and returns the token width.
+func matchWildcardRune(p []rune, c rune) (int, bool, error) {
+	switch p[0] {
+	case '?':
+		return 1, true, nil
+	case '\\':
+		if len(p) < 2 {
+			return 0, false, errBadPattern
+		}
+		return 2, p[1] == c, nil
+	case '[':
+		return matchWildcardClass(p, c)
+	default:
+		return 1, p[0] == c, nil
+	}
+}
+
+func matchWildcardClass(p []rune, c rune) (int, bool, error) {
+	i := 1
+	negated := i < len(p) && p[i] == '^'
+	if negated {
+		i++
+	}
+
+	classRune := func() (rune, error) {
+		if i >= len(p) || p[i] == ']' || p[i] == '-' {
+			return 0, errBadPattern
+		}
+		if p[i] == '\\' {
+			i++
+			if i >= len(p) {
+				return 0, errBadPattern
+			}
+		}
+		r := p[i]
+		i++
+		return r, nil
+	}
+
+	var matched bool
+	for first := true; ; first = false {
+		if i >= len(p) {
+			return 0, false, errBadPattern
+		}
+		if p[i] == ']' && !first {
+			i++
+			break
+		}
+		lo, err := classRune()
+		if err != nil {
+			return 0, false, err
+		}
+		hi := lo
+		if i < len(p) && p[i] == '-' {
+			i++
+			if hi, err = classRune(); err != nil {
+				return 0, false, err
+			}
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+
+	return i, matched != negated, nil
+}
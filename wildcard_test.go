@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestMatchWildcard(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		name    string
+		want    bool
+		err     error
+	}{
+		{"*", "", true, nil},
+		{"*", "a/b", true, nil},
+		{"[FOO]*", "[FOO]/player", false, nil},
+		{`\[FOO\]*`, "[FOO]/player", true, nil},
+		{"clan/*", "clan/bob", true, nil},
+		{"*/*", "a/b/c", true, nil},
+		{"a?c", "a/c", true, nil},
+		{"a*b*c", "axxbyyc", true, nil},
+		{"a*b*c", "axxbyy", false, nil},
+		{"[a-c]x", "bx", true, nil},
+		{"[^a-c]x", "bx", false, nil},
+		{"[^a-c]x", "/x", true, nil},
+		{"ab", "abc", false, nil},
+		{"[bad", "b", false, errBadPattern},
+		{"[]", "", false, errBadPattern},
+		{"[a-]", "a", false, errBadPattern},
+		{`x\`, "x", false, errBadPattern},
+	} {
+		got, err := MatchWildcard(tc.pattern, tc.name)
+		if err != tc.err || got != tc.want {
+			t.Errorf("MatchWildcard(%q, %q) = %v, %v; want %v, %v", tc.pattern, tc.name, got, err, tc.want, tc.err)
+		}
+	}
+}